@@ -0,0 +1,218 @@
+package main
+
+//lint:file-ignore ST1005 some user-visible messages are stored in error values and thus occasionally require capitalization
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/charlievieth/strcase"
+)
+
+// A bookmark added in fen, unlike fen.bookmarks in config.lua which are bound to the number keys.
+// They are saved in bookmarks.json next to config.lua, so the config stays hand-written
+type NamedBookmark struct {
+	Name string `json:"name"`
+	Path string `json:"path"` // Environment variables and ~ are expanded when going to it
+}
+
+func namedBookmarksFilePath() (string, error) {
+	userConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(userConfigDir, "fen", "bookmarks.json"), nil
+}
+
+var environmentVariableRegex = regexp.MustCompile(`\$\{(\w+)\}|\$(\w+)`)
+
+// Expands environment variables like $HOME or ${HOME} and a leading ~ in the path of a bookmark.
+// Variables that aren't set are kept as they are, so paths like "/mnt/$RECYCLE.BIN" still work
+func ExpandBookmarkPath(path string) string {
+	path = environmentVariableRegex.ReplaceAllStringFunc(path, func(variable string) string {
+		name := strings.Trim(variable, "${}")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return variable
+		}
+		return value
+	})
+
+	return ExpandTilde(path)
+}
+
+// Returns the named bookmarks sorted by name
+func (fen *Fen) NamedBookmarks() []NamedBookmark {
+	fen.loadNamedBookmarks()
+	return fen.namedBookmarks
+}
+
+func (fen *Fen) namedBookmarkIndex(name string) int {
+	return slices.IndexFunc(fen.namedBookmarks, func(b NamedBookmark) bool {
+		return b.Name == name
+	})
+}
+
+func validateNamedBookmarkName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("The bookmark name can't be empty")
+	}
+	return nil
+}
+
+// Adds a bookmark to path called name, and saves the bookmarks
+func (fen *Fen) AddNamedBookmark(name, path string) error {
+	if fen.config.NoWrite {
+		return errors.New("Can't add bookmarks in no-write mode")
+	}
+
+	name = strings.TrimSpace(name)
+	if err := validateNamedBookmarkName(name); err != nil {
+		return err
+	}
+
+	if err := fen.loadNamedBookmarks(); err != nil {
+		return err
+	}
+	if fen.namedBookmarkIndex(name) != -1 {
+		return errors.New("A bookmark named \"" + name + "\" already exists")
+	}
+
+	fen.namedBookmarks = append(fen.namedBookmarks, NamedBookmark{Name: name, Path: path})
+	return fen.saveNamedBookmarks()
+}
+
+// Renames the bookmark called oldName to newName, and saves the bookmarks
+func (fen *Fen) RenameNamedBookmark(oldName, newName string) error {
+	if fen.config.NoWrite {
+		return errors.New("Can't rename bookmarks in no-write mode")
+	}
+
+	newName = strings.TrimSpace(newName)
+	if err := validateNamedBookmarkName(newName); err != nil {
+		return err
+	}
+
+	if err := fen.loadNamedBookmarks(); err != nil {
+		return err
+	}
+	index := fen.namedBookmarkIndex(oldName)
+	if index == -1 {
+		return errors.New("No bookmark named \"" + oldName + "\"")
+	}
+
+	if newName != oldName && fen.namedBookmarkIndex(newName) != -1 {
+		return errors.New("A bookmark named \"" + newName + "\" already exists")
+	}
+
+	fen.namedBookmarks[index].Name = newName
+	return fen.saveNamedBookmarks()
+}
+
+// Removes the bookmark called name, and saves the bookmarks
+func (fen *Fen) RemoveNamedBookmark(name string) error {
+	if fen.config.NoWrite {
+		return errors.New("Can't remove bookmarks in no-write mode")
+	}
+
+	if err := fen.loadNamedBookmarks(); err != nil {
+		return err
+	}
+	index := fen.namedBookmarkIndex(name)
+	if index == -1 {
+		return errors.New("No bookmark named \"" + name + "\"")
+	}
+
+	fen.namedBookmarks = slices.Delete(fen.namedBookmarks, index, index+1)
+	return fen.saveNamedBookmarks()
+}
+
+// Goes to the path of the bookmark called name
+func (fen *Fen) GoNamedBookmark(name string) (string, error) {
+	fen.loadNamedBookmarks()
+	index := fen.namedBookmarkIndex(name)
+	if index == -1 {
+		return "", errors.New("No bookmark named \"" + name + "\"")
+	}
+
+	fen.DisableSelectingWithV()
+	return fen.GoPath(ExpandBookmarkPath(fen.namedBookmarks[index].Path))
+}
+
+func sortNamedBookmarks(bookmarks []NamedBookmark) {
+	slices.SortStableFunc(bookmarks, func(a, b NamedBookmark) int {
+		return strcase.Compare(a.Name, b.Name)
+	})
+}
+
+// Returns an error if bookmarks.json couldn't be read, which is also kept in fen.namedBookmarksErr.
+// Until it's fixed, we try reading it again every time, and refuse to save so it isn't overwritten
+func (fen *Fen) loadNamedBookmarks() error {
+	if fen.namedBookmarks != nil && fen.namedBookmarksErr == nil {
+		return nil
+	}
+
+	fen.namedBookmarks = []NamedBookmark{}
+	fen.namedBookmarksErr = nil
+
+	path, err := namedBookmarksFilePath()
+	if err != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		fen.namedBookmarksErr = errors.New("Failed to read bookmarks: " + err.Error())
+		return fen.namedBookmarksErr
+	}
+
+	var bookmarks []NamedBookmark
+	err = json.Unmarshal(data, &bookmarks)
+	if err != nil {
+		fen.namedBookmarksErr = errors.New("Invalid " + path + ": " + err.Error())
+		return fen.namedBookmarksErr
+	}
+
+	for _, b := range bookmarks {
+		if validateNamedBookmarkName(b.Name) == nil && b.Path != "" && fen.namedBookmarkIndex(b.Name) == -1 {
+			fen.namedBookmarks = append(fen.namedBookmarks, b)
+		}
+	}
+	sortNamedBookmarks(fen.namedBookmarks)
+	return nil
+}
+
+func (fen *Fen) saveNamedBookmarks() error {
+	sortNamedBookmarks(fen.namedBookmarks)
+
+	path, err := namedBookmarksFilePath()
+	if err != nil {
+		return err
+	}
+
+	// Indented, so it can be edited by hand
+	data, err := json.MarshalIndent(fen.namedBookmarks, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return errors.New("Failed to save bookmarks: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestNamedBookmarks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The config folder can't be changed with an environment variable on Windows")
+	}
+
+	// Keeps the bookmarks out of the real config folder
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	fen := Fen{}
+	err := fen.AddNamedBookmark(" projects ", "$HOME/projects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fen.AddNamedBookmark("Downloads", "~/Downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fen.AddNamedBookmark("projects", "/elsewhere") == nil {
+		t.Fatalf("Expected an error adding a bookmark with the same name")
+	}
+	if fen.AddNamedBookmark(" ", "/elsewhere") == nil {
+		t.Fatalf("Expected an error adding a bookmark without a name")
+	}
+
+	err = fen.RenameNamedBookmark("Downloads", "downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fen.RenameNamedBookmark("downloads", "projects") == nil {
+		t.Fatalf("Expected an error renaming a bookmark to the name of another one")
+	}
+
+	// Loads them again from the file
+	fen = Fen{}
+	expected := []NamedBookmark{{Name: "downloads", Path: "~/Downloads"}, {Name: "projects", Path: "$HOME/projects"}}
+	if !reflect.DeepEqual(fen.NamedBookmarks(), expected) {
+		t.Fatalf("Expected %v, but got %v", expected, fen.NamedBookmarks())
+	}
+
+	err = fen.RemoveNamedBookmark("downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fen.NamedBookmarks()) != 1 {
+		t.Fatalf("Expected 1 bookmark left, but got %v", fen.NamedBookmarks())
+	}
+
+	fen = Fen{config: Config{NoWrite: true}}
+	if fen.RemoveNamedBookmark("projects") == nil {
+		t.Fatalf("Expected an error removing a bookmark in no-write mode")
+	}
+
+	// A file that can't be parsed is left alone
+	path, err := namedBookmarksFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, []byte("[{"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fen = Fen{}
+	if fen.AddNamedBookmark("new", "/new") == nil || fen.namedBookmarksErr == nil {
+		t.Fatalf("Expected an error adding a bookmark when bookmarks.json is invalid")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "[{" {
+		t.Fatalf("Expected the invalid bookmarks.json to be kept, but got \"" + string(data) + "\"")
+	}
+}
+
+func TestExpandBookmarkPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("~ is not expanded on Windows")
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("FEN_TEST_FOLDER", "folder")
+	t.Setenv("FEN_TEST_UNSET", "")
+	os.Unsetenv("FEN_TEST_UNSET")
+
+	testCases := map[string]string{
+		"$HOME/${FEN_TEST_FOLDER}": filepath.Join(home, "folder"),
+		"~/$FEN_TEST_FOLDER":       filepath.Join(home, "folder"),
+		"/absolute":                "/absolute",
+		"/mnt/$FEN_TEST_UNSET.BIN": "/mnt/$FEN_TEST_UNSET.BIN",
+		"relative":                 "relative",
+	}
+
+	for path, expected := range testCases {
+		got := ExpandBookmarkPath(path)
+		if got != expected {
+			t.Fatalf("Expected \"" + path + "\" to expand to \"" + expected + "\", but got \"" + got + "\"")
+		}
+	}
+}
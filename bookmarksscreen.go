@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Lists the named bookmarks, see bookmarks.go
+type BookmarksScreen struct {
+	*tview.Box
+	fen           *Fen
+	selectedIndex int
+	scrollOffset  int
+}
+
+func NewBookmarksScreen(fen *Fen) *BookmarksScreen {
+	return &BookmarksScreen{
+		Box: tview.NewBox().SetBackgroundColor(tcell.ColorDefault),
+		fen: fen,
+	}
+}
+
+func (b *BookmarksScreen) MoveSelection(amount int) {
+	b.selectedIndex = max(0, min(len(b.fen.NamedBookmarks())-1, b.selectedIndex+amount))
+}
+
+// Selects the first bookmark if top is true, otherwise the last one
+func (b *BookmarksScreen) GoTopOrBottom(top bool) {
+	if top {
+		b.selectedIndex = 0
+	} else {
+		b.selectedIndex = max(0, len(b.fen.NamedBookmarks())-1)
+	}
+}
+
+// Returns false if there are no bookmarks
+func (b *BookmarksScreen) Selected() (NamedBookmark, bool) {
+	bookmarks := b.fen.NamedBookmarks()
+	if len(bookmarks) == 0 {
+		return NamedBookmark{}, false
+	}
+
+	return bookmarks[max(0, min(len(bookmarks)-1, b.selectedIndex))], true
+}
+
+// Selects the bookmark called name, used after adding or renaming it since the bookmarks are sorted by name
+func (b *BookmarksScreen) SelectName(name string) {
+	for i, bookmark := range b.fen.NamedBookmarks() {
+		if bookmark.Name == name {
+			b.selectedIndex = i
+			return
+		}
+	}
+}
+
+func (b *BookmarksScreen) Draw(screen tcell.Screen) {
+	x, y, w, h := b.GetInnerRect()
+	b.Box.SetRect(x, y+1, w, h-2)
+	b.Box.DrawForSubclass(screen, b)
+	y++
+	h -= 2
+
+	bookmarks := b.fen.NamedBookmarks()
+
+	tview.Print(screen, "[::r] Bookmarks [::-]", x, y, w, tview.AlignLeft, tcell.ColorDefault)
+	tview.Print(screen, strconv.Itoa(len(bookmarks))+" bookmarks", x, y, w, tview.AlignRight, tcell.ColorDefault)
+
+	helpText := "[::d]Enter/l: go  a: bookmark the current folder  r: rename  x: remove  q: close"
+	tview.Print(screen, helpText, x, y+h-1, w, tview.AlignLeft, tcell.ColorDefault)
+
+	if b.fen.namedBookmarksErr != nil {
+		tview.Print(screen, "[red]"+tview.Escape(b.fen.namedBookmarksErr.Error()), x+1, y+2, w-1, tview.AlignLeft, tcell.ColorDefault)
+		tview.Print(screen, "[::d]Bookmarks can't be changed until the file is fixed", x+1, y+3, w-1, tview.AlignLeft, tcell.ColorDefault)
+		return
+	}
+
+	if len(bookmarks) == 0 {
+		tview.Print(screen, "[::d]No bookmarks yet, press a to bookmark the current folder", x+1, y+2, w, tview.AlignLeft, tcell.ColorDefault)
+		return
+	}
+
+	b.selectedIndex = max(0, min(len(bookmarks)-1, b.selectedIndex))
+
+	listY := y + 2
+	listHeight := max(1, h-4)
+	if b.selectedIndex < b.scrollOffset {
+		b.scrollOffset = b.selectedIndex
+	} else if b.selectedIndex >= b.scrollOffset+listHeight {
+		b.scrollOffset = b.selectedIndex - listHeight + 1
+	}
+	b.scrollOffset = max(0, min(b.scrollOffset, len(bookmarks)-1))
+
+	nameWidth := 0
+	for _, bookmark := range bookmarks {
+		nameWidth = max(nameWidth, len([]rune(bookmark.Name)))
+	}
+	nameWidth = min(nameWidth, w/3)
+
+	for i, bookmark := range bookmarks[b.scrollOffset:min(len(bookmarks), b.scrollOffset+listHeight)] {
+		name := []rune(bookmark.Name)
+		name = name[:min(len(name), nameWidth)]
+		line := " " + tview.Escape(string(name)) + strings.Repeat(" ", nameWidth-len(name)) + "  [::d]" + tview.Escape(bookmark.Path)
+
+		// The path as it was saved is shown, with the expanded path if it contains environment variables
+		if expanded := ExpandBookmarkPath(bookmark.Path); expanded != bookmark.Path {
+			line += " (" + tview.Escape(expanded) + ")"
+		}
+
+		if b.scrollOffset+i == b.selectedIndex {
+			line = "[::r]" + strings.ReplaceAll(line, "[::d]", "[::rd]")
+		}
+		tview.Print(screen, line, x, listY+i, w, tview.AlignLeft, tcell.ColorDefault)
+	}
+}
@@ -43,6 +43,11 @@ func (bottomBar *BottomBar) Draw(screen tcell.Screen) {
 
 	freeBytesStr += " free"
 
+	// The size of the current folder beside the free disk space
+	if bottomBar.fen.config.FolderSizes {
+		freeBytesStr = bottomBar.fen.FolderSizeText(bottomBar.fen.wd, false) + " here, " + freeBytesStr
+	}
+
 	if bottomBar.alternateText != "" {
 		tview.Print(screen, "[teal:]"+tview.Escape(bottomBar.alternateText), x, y, w, tview.AlignLeft, tcell.ColorDefault)
 	}
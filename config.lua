@@ -9,18 +9,29 @@ fen.split_home_end = false -- Only applies when fen.folders_first = true, splits
 fen.terminal_title = true -- Only applies to Linux, sets the terminal title to "fen <version>"
 fen.show_hostname = true -- Does not apply to Windows, shows username@hostname in the top left
 fen.show_help_text = true
-fen.sort_by = "alphabetical" -- "fen -h" for valid values
+fen.sort_by = "alphabetical" -- "fen -h" for valid values, multiple can be combined with commas like "file-extension,size"
 fen.sort_reverse = false
+fen.remember_view_settings = false -- The sorting, hidden files and filter are changed per folder (s, S, z, | keys) and remembered, otherwise they apply to every folder
 fen.file_event_interval_ms = 300 -- How often to update the screen on file events (and job count updates), if set to 0, it updates on every event
 fen.always_show_info_numbers = false -- Shows the blue, green and yellow numbers in the bottom right even when they are 0
 fen.scroll_speed = 2 -- When scrolling faster than 30ms per scroll, scroll this many entries
-fen.git_status = false -- When true, unstaged/untracked files in local git repositories are shown in red
+fen.git_status = false -- When true, changed files in local git repositories are colored: conflicted in bright red, unstaged in red, untracked in yellow, staged in green and ignored dimmed. Folders get the color of the files inside them. The top bar shows the branch, commits ahead (↑) and behind (↓) the upstream branch, and + for staged, * for unstaged, ? for untracked and ! for conflicted files
+fen.git_status_cache_mb = 64 -- How much memory (roughly) to spend remembering the changed files of git repositories you've visited. When over budget, or over 50 repositories, the ones that are quickest to git status are forgotten first. If set to 0, only the current repository is remembered
 fen.preview_safety_blocklist = true -- Prevents common sensitive file types from being previewed
 fen.close_on_escape = false -- Use the Escape key to close fen, useful for embedding in other applications
 fen.file_size_in_all_panes = false
+fen.folder_sizes = false -- Calculate the size of folders in the background (like "du"), instead of showing how many files they contain
 fen.file_size_format = "human-readable" -- "fen -h" for valid values
 fen.pause_on_open_file = true -- Set this to false to disable the "Press any key to continue..." prompt after having opened a file
 fen.filename_search_case = "insensitive" -- "insensitive", "sensitive"
+fen.search_case = "insensitive" -- "insensitive", "sensitive", "smart" (case-sensitive only when the search contains an uppercase letter)
+fen.search_regex = false -- Use regular expressions in the "/" search, can also be toggled with Ctrl+T while searching
+fen.filename_search_index = false -- Store the filenames found by the search filenames popup in the user cache folder, so searching the same folder again is instant
+fen.filename_search_follow_symlinks = false -- Search inside symlinked folders too, shows the real path of files found through them. fen.filename_search_index is not used when this is true
+fen.pane_proportions = {1, 3, 3} -- The widths of the parent, middle and preview panes relative to eachother, 0 hides the parent or preview pane
+fen.show_parent_pane = true
+fen.hide_empty_preview_pane = false -- Hide the preview pane when there is nothing to show in it, giving the middle pane more room
+fen.columns = {"size"} -- Shown to the right of the filenames in the middle pane: "permissions", "owner", "size", "disk-usage", "modified", "git" (the 2-letter state from "git status --short"), "link". Columns are left out from the start of the list when the pane is too narrow
 
 -- Everything below this line is non-default examples
 
@@ -36,9 +47,22 @@ print(fen.config_path) -- Something like "/home/YOUR_USER/.config/fen/" (always
 -- The OS-specific home folder (nil if not found), details: https://pkg.go.dev/os#UserHomeDir
 print(fen.home_path) -- Something like "/home/YOUR_USER/" (always ends in a slash)
 
+-- Sort specific folders differently than fen.sort_by
+fen.folder_sort_by = {
+	{
+		folder = fen.home_path .. "Downloads",
+		sort_by = "modified",
+	},
+	{
+		folder = "~/releases",
+		sort_by = "natural",
+	},
+}
+
 -- When pressing a number key (0-9), go to the specified folder or file path
--- It can also be a relative path which can be used in any folder
+-- It can also be a relative path which can be used in any folder, and environment variables like $HOME are expanded when they are set
 -- This is a list with no more than 10 elements
+-- For more bookmarks, press B to add named bookmarks in fen. They are saved in bookmarks.json next to this file
 fen.bookmarks = {
 	[1] = fen.home_path,
 	[2] = fen.config_path .. "config.lua",
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Shows what is taking up disk space in a folder, like ncdu
+type DiskUsageScreen struct {
+	*tview.Box
+	fen *Fen
+
+	mutex        sync.Mutex
+	root         *diskUsageNode
+	current      *diskUsageNode // The folder shown
+	selected     *diskUsageNode // nil when current is empty
+	scrollOffset int
+	apparentSize bool // Show the sum of the file sizes instead of the space allocated on disk
+	scanning     bool
+	filesScanned int
+	cancelFunc   context.CancelFunc
+	scanDone     chan struct{} // Closed when the scan goroutine returns
+	lastDrawTime time.Time
+}
+
+type diskUsageNode struct {
+	name     string // The full path for the root
+	isDir    bool
+	apparent uint64
+	onDisk   uint64
+	files    int // Counting the files in every subfolder
+	parent   *diskUsageNode
+	children []*diskUsageNode
+	removed  bool // Deleted while scanning, so the scan doesn't add sizes to the folders it was in
+}
+
+func (node *diskUsageNode) path() string {
+	if node.parent == nil {
+		return node.name
+	}
+	return filepath.Join(node.parent.path(), node.name)
+}
+
+// Returns true if node or a folder containing it was removed
+func (node *diskUsageNode) isRemoved() bool {
+	for n := node; n != nil; n = n.parent {
+		if n.removed {
+			return true
+		}
+	}
+	return false
+}
+
+// Adds to the sizes of node and every folder containing it
+func (node *diskUsageNode) addSize(apparent, onDisk uint64, files int) {
+	for n := node; n != nil; n = n.parent {
+		n.apparent += apparent
+		n.onDisk += onDisk
+		n.files += files
+	}
+}
+
+func NewDiskUsageScreen(fen *Fen, path string) *DiskUsageScreen {
+	d := &DiskUsageScreen{
+		Box: tview.NewBox().SetBackgroundColor(tcell.ColorDefault),
+		fen: fen,
+	}
+
+	d.Scan(path)
+	return d
+}
+
+// Starts scanning path in the background, cancelling the previous scan.
+// The new scan waits for the previous one to stop, so they never run at the same time
+func (d *DiskUsageScreen) Scan(path string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.cancelFunc != nil {
+		d.cancelFunc()
+	}
+	previousScanDone := d.scanDone
+
+	var ctx context.Context
+	ctx, d.cancelFunc = context.WithCancel(context.Background())
+
+	d.root = &diskUsageNode{name: path, isDir: true}
+	d.current = d.root
+	d.selected = nil
+	d.scrollOffset = 0
+	d.scanning = true
+	d.filesScanned = 0
+
+	root := d.root
+	scanDone := make(chan struct{})
+	d.scanDone = scanDone
+	go func() {
+		defer close(scanDone)
+
+		if previousScanDone != nil {
+			<-previousScanDone
+		}
+
+		stat, err := os.Lstat(path)
+		if err == nil {
+			d.mutex.Lock()
+			root.addSize(uint64(stat.Size()), FileDiskUsage(stat), 0)
+			d.mutex.Unlock()
+
+			device, hasDevice := GetFileID(stat)
+			d.scanFolder(ctx, root, path, device.Device, hasDevice)
+		}
+
+		d.mutex.Lock()
+		if ctx.Err() != nil {
+			d.mutex.Unlock()
+			return
+		}
+		d.scanning = false
+
+		// The panes can show the folder sizes we found, see fen.folder_sizes
+		d.fen.folderSizeHandler.Store(root.path(), FolderSize{Apparent: root.apparent, OnDisk: root.onDisk})
+		for _, child := range root.children {
+			if child.isDir {
+				d.fen.folderSizeHandler.Store(child.path(), FolderSize{Apparent: child.apparent, OnDisk: child.onDisk})
+			}
+		}
+		d.mutex.Unlock()
+
+		d.fen.app.QueueUpdateDraw(func() {})
+	}()
+}
+
+// Like "du -x", other mounted file systems are skipped and symlinks aren't followed
+func (d *DiskUsageScreen) scanFolder(ctx context.Context, node *diskUsageNode, path string, device uint64, hasDevice bool) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return
+	}
+
+	children := make([]*diskUsageNode, 0, len(entries))
+	var subfolders []*diskUsageNode
+	var apparent, onDisk uint64
+	files := 0
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		child := &diskUsageNode{name: entry.Name(), isDir: entry.IsDir(), parent: node, apparent: uint64(info.Size()), onDisk: FileDiskUsage(info)}
+		children = append(children, child)
+		apparent += child.apparent
+		onDisk += child.onDisk
+
+		if !child.isDir {
+			files++
+			continue
+		}
+
+		id, ok := GetFileID(info)
+		if !hasDevice || !ok || id.Device == device {
+			subfolders = append(subfolders, child)
+		}
+	}
+
+	// The subfolders already include their own size, what's inside them is added as they are scanned
+	// Checked while holding the mutex, since Scan() resets the counters right after cancelling
+	d.mutex.Lock()
+	if ctx.Err() != nil || node.isRemoved() {
+		d.mutex.Unlock()
+		return
+	}
+	node.children = children
+	node.addSize(apparent, onDisk, files)
+	d.filesScanned += len(entries)
+
+	shouldDraw := time.Since(d.lastDrawTime) > 200*time.Millisecond
+	if shouldDraw {
+		d.lastDrawTime = time.Now()
+	}
+	d.mutex.Unlock()
+
+	if shouldDraw {
+		d.fen.app.QueueUpdateDraw(func() {})
+	}
+
+	for _, subfolder := range subfolders {
+		if ctx.Err() != nil {
+			return
+		}
+
+		d.scanFolder(ctx, subfolder, filepath.Join(path, subfolder.name), device, hasDevice)
+	}
+}
+
+// Stops scanning, used when closing the screen
+func (d *DiskUsageScreen) Cancel() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.cancelFunc != nil {
+		d.cancelFunc()
+	}
+}
+
+func (d *DiskUsageScreen) sizeOf(node *diskUsageNode) uint64 {
+	if d.apparentSize {
+		return node.apparent
+	}
+	return node.onDisk
+}
+
+// Sorts the entries of the current folder by size, biggest first
+// You need to manually lock / unlock the mutex to use this function
+func (d *DiskUsageScreen) sortedChildren() []*diskUsageNode {
+	slices.SortStableFunc(d.current.children, func(a, b *diskUsageNode) int {
+		result := cmp.Compare(d.sizeOf(b), d.sizeOf(a))
+		if result != 0 {
+			return result
+		}
+		return strings.Compare(a.name, b.name)
+	})
+
+	return d.current.children
+}
+
+// Moves the selection by amount entries, clamped to the first and last entry
+func (d *DiskUsageScreen) MoveSelection(amount int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	children := d.sortedChildren()
+	if len(children) == 0 {
+		return
+	}
+
+	index := max(0, min(len(children)-1, slices.Index(children, d.selected)+amount))
+	d.selected = children[index]
+}
+
+// Selects the first entry if top is true, otherwise the last one
+func (d *DiskUsageScreen) GoTopOrBottom(top bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	children := d.sortedChildren()
+	if len(children) == 0 {
+		return
+	}
+
+	if top {
+		d.selected = children[0]
+	} else {
+		d.selected = children[len(children)-1]
+	}
+}
+
+// Shows the selected folder, returns false if a file is selected
+func (d *DiskUsageScreen) GoRight() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.selected == nil || !d.selected.isDir {
+		return false
+	}
+
+	d.current = d.selected
+	d.selected = nil
+	d.scrollOffset = 0
+	return true
+}
+
+// Goes back to the folder containing the current one, but not above the scanned folder
+func (d *DiskUsageScreen) GoLeft() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.current.parent == nil {
+		return
+	}
+
+	d.selected = d.current
+	d.current = d.current.parent
+	d.scrollOffset = 0
+}
+
+func (d *DiskUsageScreen) ToggleApparentSize() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.apparentSize = !d.apparentSize
+}
+
+// Returns the path of the selected entry, or false if nothing is selected
+func (d *DiskUsageScreen) SelectedPath() (string, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.selected == nil {
+		return "", false
+	}
+	return d.selected.path(), true
+}
+
+// Returns the path of the scanned folder
+func (d *DiskUsageScreen) RootPath() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.root.path()
+}
+
+// Removes the selected entry after deleting it, subtracting its size from the folders containing it
+func (d *DiskUsageScreen) RemoveSelected() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	removed := d.selected
+	if removed == nil {
+		return
+	}
+	removed.removed = true
+
+	for n := removed.parent; n != nil; n = n.parent {
+		n.apparent -= min(n.apparent, removed.apparent)
+		n.onDisk -= min(n.onDisk, removed.onDisk)
+		n.files -= min(n.files, removed.files)
+		if !removed.isDir {
+			n.files = max(0, n.files-1)
+		}
+	}
+
+	children := d.sortedChildren()
+	index := slices.Index(children, removed)
+	d.current.children = slices.Delete(children, index, index+1)
+
+	d.selected = nil
+	if len(d.current.children) > 0 {
+		d.selected = d.current.children[min(index, len(d.current.children)-1)]
+	}
+}
+
+// Returns the percentage bar of a size relative to total, like "[#####     ]"
+func diskUsageBar(size, total uint64, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = int(float64(size) / float64(total) * float64(width))
+	}
+	filled = max(0, min(width, filled))
+
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(" ", width-filled) + "]"
+}
+
+func (d *DiskUsageScreen) Draw(screen tcell.Screen) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	x, y, w, h := d.GetInnerRect()
+	d.Box.SetRect(x, y+1, w, h-2)
+	d.Box.DrawForSubclass(screen, d)
+	y++
+	h -= 2
+
+	sizeKind := "on disk"
+	if d.apparentSize {
+		sizeKind = "apparent size"
+	}
+
+	title := "[::r] Disk usage [::-] " + tview.Escape(d.current.path())
+	tview.Print(screen, title, x, y, w, tview.AlignLeft, tcell.ColorDefault)
+
+	total := d.sizeOf(d.current)
+	status := BytesToFileSizeFormat(total, 2, d.fen.config.FileSizeFormat) + " " + sizeKind + ", " + strconv.Itoa(d.current.files) + " files"
+	if d.scanning {
+		status = "[yellow]scanning... " + strconv.Itoa(d.filesScanned) + " entries[-] " + status
+	}
+	tview.Print(screen, status, x, y, w, tview.AlignRight, tcell.ColorDefault)
+
+	helpText := "[::d]Enter/l: open  h: back  a: apparent size/on disk  x: delete  r: rescan  q: close"
+	tview.Print(screen, helpText, x, y+h-1, w, tview.AlignLeft, tcell.ColorDefault)
+
+	children := d.sortedChildren()
+	if len(children) == 0 {
+		if !d.scanning {
+			tview.Print(screen, "[:red]empty", x+1, y+2, w, tview.AlignLeft, tcell.ColorDefault)
+		}
+		return
+	}
+
+	if d.selected == nil {
+		d.selected = children[0]
+	}
+
+	listY := y + 2
+	listHeight := max(1, h-4)
+	selectedIndex := max(0, slices.Index(children, d.selected))
+	if selectedIndex < d.scrollOffset {
+		d.scrollOffset = selectedIndex
+	} else if selectedIndex >= d.scrollOffset+listHeight {
+		d.scrollOffset = selectedIndex - listHeight + 1
+	}
+	d.scrollOffset = max(0, min(d.scrollOffset, len(children)-1))
+
+	const barWidth = 20
+	for i, child := range children[d.scrollOffset:min(len(children), d.scrollOffset+listHeight)] {
+		size := d.sizeOf(child)
+
+		percentage := 0.0
+		if total > 0 {
+			percentage = float64(size) / float64(total) * 100
+		}
+		percentageText := strconv.FormatFloat(percentage, 'f', 1, 64) + "%"
+
+		sizeText := BytesToFileSizeFormat(size, 2, d.fen.config.FileSizeFormat)
+		line := strings.Repeat(" ", max(0, 10-len(sizeText))) + sizeText + " " + strings.Repeat(" ", max(0, 6-len(percentageText))) + percentageText + " " + diskUsageBar(size, total, barWidth) + " "
+
+		childPath := filepath.Join(d.current.path(), child.name)
+		stat, _ := os.Lstat(childPath)
+		style := FileColor(stat, childPath)
+		if child == d.selected {
+			style = style.Reverse(true)
+		}
+
+		name := child.name
+		if child.isDir {
+			name += string(os.PathSeparator)
+		}
+
+		tview.Print(screen, tview.Escape(line), x, listY+i, w, tview.AlignLeft, tcell.ColorDefault)
+		nameX := x + len([]rune(line))
+		for j, c := range []rune(name) {
+			if nameX+j >= x+w {
+				break
+			}
+			screen.SetContent(nameX+j, listY+i, c, nil, style)
+		}
+	}
+}
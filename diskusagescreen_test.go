@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+func TestDiskUsageScreenScan(t *testing.T) {
+	folder := t.TempDir()
+	os.MkdirAll(filepath.Join(folder, "big", "deeper"), 0o755)
+	os.WriteFile(filepath.Join(folder, "small"), make([]byte, 10), 0o644)
+	os.WriteFile(filepath.Join(folder, "big", "deeper", "file"), make([]byte, 5000), 0o644)
+
+	fen := Fen{app: tview.NewApplication()}
+	d := &DiskUsageScreen{fen: &fen, apparentSize: true, lastDrawTime: time.Now()} // QueueUpdateDraw() would block, since the app isn't running
+	d.root = &diskUsageNode{name: folder, isDir: true}
+	d.current = d.root
+	d.scanFolder(context.Background(), d.root, folder, 0, false)
+
+	if d.root.files != 2 {
+		t.Fatal("Expected 2 files, but got", d.root.files)
+	}
+
+	children := d.sortedChildren()
+	if len(children) != 2 || children[0].name != "big" || children[1].name != "small" {
+		t.Fatal("Expected the big folder first, but got", children)
+	}
+
+	bigStat, _ := os.Lstat(filepath.Join(folder, "big"))
+	deeperStat, _ := os.Lstat(filepath.Join(folder, "big", "deeper"))
+	expectedBigSize := uint64(bigStat.Size()+deeperStat.Size()) + 5000
+	if children[0].apparent != expectedBigSize {
+		t.Fatal("Expected the big folder to be", expectedBigSize, "bytes, but got", children[0].apparent)
+	}
+
+	if children[0].children[0].path() != filepath.Join(folder, "big", "deeper") {
+		t.Fatal("Expected the path of the deeper folder, but got", children[0].children[0].path())
+	}
+
+	// Deleting subtracts the size from the folders containing it
+	rootSizeBefore := d.root.apparent
+	big := children[0]
+	d.selected = big
+	d.RemoveSelected()
+	if d.root.apparent != rootSizeBefore-expectedBigSize || d.root.files != 1 {
+		t.Fatal("Expected the size of the big folder to be subtracted, but got", d.root.apparent, d.root.files)
+	}
+	if len(d.root.children) != 1 || d.selected != d.root.children[0] {
+		t.Fatal("Expected the remaining entry to be selected")
+	}
+
+	// A scan still going through the removed folder doesn't add its size back
+	rootSizeBefore = d.root.apparent
+	d.scanFolder(context.Background(), big.children[0], filepath.Join(folder, "big", "deeper"), 0, false)
+	if d.root.apparent != rootSizeBefore {
+		t.Fatal("Expected the size to stay the same after scanning a removed folder, but got", d.root.apparent)
+	}
+}
+
+func TestDiskUsageBar(t *testing.T) {
+	if bar := diskUsageBar(1, 4, 8); bar != "[##      ]" {
+		t.Fatal("Expected a quarter filled bar, but got", bar)
+	}
+	if bar := diskUsageBar(0, 0, 4); bar != "[    ]" {
+		t.Fatal("Expected an empty bar, but got", bar)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+)
+
+// Enables or disables dual-pane mode, enabling it opens a second tab if there is only one
+func (fen *Fen) ToggleDualPane() {
+	fen.DisableSelectingWithV()
+
+	if fen.dualPane {
+		fen.dualPane = false
+		fen.otherTab = nil
+		fen.otherPane.StopWatching()
+		fen.UpdateLayout()
+		return
+	}
+
+	if len(fen.tabs) < 2 {
+		fen.NewTab()
+		fen.SwitchTab(fen.currentTab - 1)
+	}
+
+	// Show the next tab in the other panel
+	fen.otherTab = fen.tabs[(fen.currentTab+1)%len(fen.tabs)]
+	fen.otherPane.SetFlattened(fen.otherTab.flattened)
+
+	fen.dualPane = true
+	fen.dualPanePanels = [2]*FilesPane{fen.middlePane, fen.otherPane}
+	fen.UpdateLayout()
+}
+
+// Makes the other panel the active one in dual-pane mode
+func (fen *Fen) SwitchPanel() {
+	if !fen.dualPane {
+		return
+	}
+
+	otherTabIndex := slices.Index(fen.tabs, fen.otherTab)
+	if otherTabIndex == -1 {
+		panic("In SwitchPanel(): fen.otherTab was not in fen.tabs")
+	}
+
+	fen.saveCurrentTab()
+	fen.otherTab = fen.tabs[fen.currentTab]
+
+	// Each panel keeps its own pane, so the cursor and scroll position stay the same
+	fen.middlePane, fen.otherPane = fen.otherPane, fen.middlePane
+	fen.loadTab(otherTabIndex)
+}
+
+// Returns the folder of the other panel in dual-pane mode, which copying and moving files to the other panel uses
+func (fen *Fen) OtherPanelFolder() (string, error) {
+	if !fen.dualPane {
+		return "", errors.New("Not in dual-pane mode")
+	}
+
+	return fen.otherTab.wd, nil
+}
+
+// Reads the folder of the other panel and keeps its selection up to date, called by UpdatePanes()
+func (fen *Fen) updateOtherPane(forceReadDir bool) {
+	fen.otherPane.ChangeDir(fen.otherTab.wd, forceReadDir)
+
+	index := fen.otherPane.GetSelectedIndexFromEntry(fen.otherPane.EntryNameFromPath(fen.otherTab.sel))
+	if index != -1 {
+		fen.otherPane.SetSelectedEntryFromIndex(index)
+		return
+	}
+
+	// The selected file in the other panel was removed
+	fen.otherPane.keepSelectionInBounds()
+	entryName := fen.otherPane.GetSelectedEntryFromIndex(fen.otherPane.selectedEntryIndex)
+	if entryName != "" {
+		fen.otherTab.sel = filepath.Join(fen.otherTab.wd, entryName)
+	}
+}
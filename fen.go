@@ -12,10 +12,10 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 
-	"github.com/kivattt/gogitstatus"
 	"github.com/rivo/tview"
 	"github.com/yuin/gluamapper"
 	lua "github.com/yuin/gopher-lua"
@@ -28,7 +28,8 @@ type Fen struct {
 	sel              string
 	lastSel          string
 	lastInRepository string
-	history          History
+	history          *History
+	navigation       *NavigationStack // The working directories visited, for Fen.GoBack() and Fen.GoForward()
 
 	selected     map[string]bool
 	yankSelected map[string]bool
@@ -48,15 +49,57 @@ type Fen struct {
 	librariesScreenVisible *bool
 
 	runningGitStatus bool
+	gitPreview       int // One of GIT_PREVIEW_*, the right pane shows the git diff, log or blame of the selected file instead of previewing it
+
+	gitHistoryPreview gitHistoryPreview // The last git log or blame shown, see Fen.GitHistoryPreview()
+	gitDiffPreview    gitDiffPreview    // The last git diff shown, see Fen.GitDiffPreview()
+
+	changedFilesOnly bool // Only show changed files and the folders containing them in the middle and right panes, see FilesPane.FilterAndSortEntries()
+
+	namedBookmarks    []NamedBookmark // Loaded on first use, see bookmarks.go
+	namedBookmarksErr error           // Set when bookmarks.json couldn't be read, the bookmarks aren't saved until it's fixed
+
+	visitedFolders map[string]*VisitedFolder // The keys are folder paths, remembered across sessions, see navigationhistory.go
 
 	folderFileCountCache map[string]int
 
+	searchMatcher *SearchMatcher // The last "/" search, nil if there is none. Matches are highlighted in the middle pane
+
+	inputHistories map[string][]string // The keys are the INPUT_HISTORY_ constants, loaded from disk on first use
+
+	flattened bool // File list mode, the middle pane recursively lists every file under fen.wd
+
+	tabs       []*Tab // The state of the current tab is kept in the fields above, and only stored in its Tab when switching away from it
+	currentTab int
+
+	filters map[string]*SearchMatcher // The middle pane only shows entries matching the filter of its folder, see SetFilter()
+
+	folderViewSettings map[string]*FolderViewSettings // The keys are folder paths, see viewsettings.go
+
+	folderSizeHandler FolderSizeHandler
+
 	topBar     *TopBar
 	bottomBar  *BottomBar
 	leftPane   *FilesPane
 	middlePane *FilesPane
 	rightPane  *FilesPane
 
+	filePanesFlex *tview.Flex  // Holds the panes shown for the current layout, see UpdateLayout()
+	layout        []layoutItem // What was last put in filePanesFlex, so it is only rebuilt when the layout changes
+
+	// Remembers RightPaneHasSomethingToShow() for a selected file, since it is checked on every UpdatePanes()
+	previewableSel      string
+	previewableSelValue bool
+
+	// Dual-pane mode shows two tabs side by side as panels, like Midnight Commander.
+	// fen.middlePane is always the active panel, and the panes are swapped when switching panels
+	dualPane       bool
+	otherPane      *FilesPane    // The inactive panel
+	otherTab       *Tab          // The tab shown in the inactive panel
+	dualPanePanels [2]*FilesPane // The panels from left to right, so they don't move around when switching
+
+	middlePaneMaximized bool
+
 	showHomePathAsTilde bool
 }
 
@@ -73,21 +116,64 @@ var ConfigKeysByTagNameNotToIncludeInOptionsMenu = []string{
 const (
 	CASE_INSENSITIVE = "insensitive"
 	CASE_SENSITIVE   = "sensitive"
+	CASE_SMART       = "smart" // Case-sensitive only if the search term contains an uppercase letter, only used for fen.search_case
 )
 
 var ValidFilenameSearchCaseValues = [...]string{CASE_INSENSITIVE, CASE_SENSITIVE}
+var ValidSearchCaseValues = [...]string{CASE_INSENSITIVE, CASE_SENSITIVE, CASE_SMART}
 
 const (
 	// SORT_NONE should only be used if fen is too slow loading big folders, because it messes with some things
-	SORT_NONE           = "none" // TODO: Make SORT_NONE also disable the implicit sorting of os.ReadDir()
-	SORT_ALPHABETICAL   = "alphabetical"
-	SORT_MODIFIED       = "modified"
-	SORT_SIZE           = "size"
-	SORT_FILE_EXTENSION = "file-extension"
-	SORT_NUMBER         = "number"
+	SORT_NONE                          = "none" // TODO: Make SORT_NONE also disable the implicit sorting of os.ReadDir()
+	SORT_ALPHABETICAL                  = "alphabetical"
+	SORT_ALPHABETICAL_CASE_INSENSITIVE = "alphabetical-case-insensitive"
+	SORT_NATURAL                       = "natural" // Compares numbers anywhere in the name by their value, so "v1.10" comes after "v1.9"
+	SORT_MODIFIED                      = "modified"
+	SORT_CREATED                       = "created"  // Not available on every file system
+	SORT_ACCESSED                      = "accessed" // Not available on every file system
+	SORT_SIZE                          = "size"
+	SORT_FILE_EXTENSION                = "file-extension"
+	SORT_NUMBER                        = "number"
+	SORT_TYPE                          = "type"       // Folders, symlinks, files and then everything else (devices, sockets...)
+	SORT_PATH_DEPTH                    = "path-depth" // Only used in the search filenames popup
+)
+
+var ValidSortByValues = [...]string{SORT_NONE, SORT_ALPHABETICAL, SORT_ALPHABETICAL_CASE_INSENSITIVE, SORT_NATURAL, SORT_NUMBER, SORT_MODIFIED, SORT_CREATED, SORT_ACCESSED, SORT_SIZE, SORT_FILE_EXTENSION, SORT_TYPE}
+
+// Multiple sort keys can be combined with commas, like "file-extension,size".
+// Entries equal by the first key are sorted by the next key, and lastly alphabetically
+func SortByKeys(sortBy string) []string {
+	return strings.Split(sortBy, ",")
+}
+
+// Returns an error describing the first invalid sort key in sortBy
+func ValidateSortBy(sortBy string) error {
+	sortKeys := SortByKeys(sortBy)
+	for _, sortKey := range sortKeys {
+		if !slices.Contains(ValidSortByValues[:], sortKey) {
+			return errors.New("Invalid sort_by value \"" + sortKey + "\"")
+		}
+
+		if sortKey == SORT_NONE && len(sortKeys) > 1 {
+			return errors.New("Invalid sort_by value \"" + sortBy + "\", \"" + SORT_NONE + "\" can't be combined with other values")
+		}
+	}
+
+	return nil
+}
+
+// The columns shown to the right of the filenames in the middle pane, configured with fen.columns
+const (
+	COLUMN_PERMISSIONS = "permissions"
+	COLUMN_OWNER       = "owner" // "user:group"
+	COLUMN_SIZE        = "size"
+	COLUMN_DISK_USAGE  = "disk-usage" // The space allocated on disk, folders only show it when fen.folder_sizes is true
+	COLUMN_MODIFIED    = "modified"
+	COLUMN_GIT         = "git"  // The state like in "git status --short" when fen.git_status is true, see GitFileState.ShortText()
+	COLUMN_LINK        = "link" // The symlink target
 )
 
-var ValidSortByValues = [...]string{SORT_NONE, SORT_ALPHABETICAL, SORT_NUMBER, SORT_MODIFIED, SORT_SIZE, SORT_FILE_EXTENSION}
+var ValidColumnValues = [...]string{COLUMN_PERMISSIONS, COLUMN_OWNER, COLUMN_SIZE, COLUMN_DISK_USAGE, COLUMN_MODIFIED, COLUMN_GIT, COLUMN_LINK}
 
 const (
 	HUMAN_READABLE = "human-readable"
@@ -103,11 +189,17 @@ type PreviewOrOpenEntry struct {
 	DoNotMatch []string
 }
 
+type FolderSortByEntry struct {
+	Folder string
+	SortBy string
+}
+
 type Config struct {
 	UiBorders               bool                 `lua:"ui_borders"`
 	Mouse                   bool                 `lua:"mouse"`
 	NoWrite                 bool                 `lua:"no_write"`
 	HiddenFiles             bool                 `lua:"hidden_files"`
+	RememberViewSettings    bool                 `lua:"remember_view_settings"` /* Sorting, hidden files and filters are changed per folder and remembered, see viewsettings.go */
 	FoldersFirst            bool                 `lua:"folders_first"`
 	SplitHomeEnd            bool                 `lua:"split_home_end"`
 	PrintPathOnOpen         bool                 `lua:"print_path_on_open"`
@@ -116,19 +208,30 @@ type Config struct {
 	ShowHostname            bool                 `lua:"show_hostname"`
 	Open                    []PreviewOrOpenEntry `lua:"open"`
 	Preview                 []PreviewOrOpenEntry `lua:"preview"`
-	SortBy                  string               `lua:"sort_by"` /* Valid values defined in ValidSortByValues */
+	SortBy                  string               `lua:"sort_by"`        /* Valid values defined in ValidSortByValues, can be combined with commas. See SortByKeys() */
+	FolderSortBy            []FolderSortByEntry  `lua:"folder_sort_by"` /* Overrides fen.sort_by for specific folders */
 	SortReverse             bool                 `lua:"sort_reverse"`
 	FileEventIntervalMillis int                  `lua:"file_event_interval_ms"`
 	AlwaysShowInfoNumbers   bool                 `lua:"always_show_info_numbers"`
 	ScrollSpeed             int                  `lua:"scroll_speed"`
 	Bookmarks               [10]string           `lua:"bookmarks"`
 	GitStatus               bool                 `lua:"git_status"`
+	GitStatusCacheMegabytes int                  `lua:"git_status_cache_mb"` /* Memory budget for the changed files of git repositories, see gitstatuscache.go */
 	PreviewSafetyBlocklist  bool                 `lua:"preview_safety_blocklist"`
 	CloseOnEscape           bool                 `lua:"close_on_escape"`
 	FileSizeInAllPanes      bool                 `lua:"file_size_in_all_panes"`
 	FileSizeFormat          string               `lua:"file_size_format"` /* Valid values defined in ValidFileSizeFormatValues */
 	PauseOnOpenFile         bool                 `lua:"pause_on_open_file"`
 	FilenameSearchCase      string               `lua:"filename_search_case"` /* Valid values defined in ValidFilenameSearchCaseValues */
+	FilenameSearchIndex     bool                 `lua:"filename_search_index"`
+	SearchCase              string               `lua:"search_case"` /* Valid values defined in ValidSearchCaseValues */
+	SearchRegex             bool                 `lua:"search_regex"`
+	SearchFollowSymlinks    bool                 `lua:"filename_search_follow_symlinks"`
+	PaneProportions         [3]int               `lua:"pane_proportions"` /* The widths of the parent, middle and preview panes relative to eachother */
+	ShowParentPane          bool                 `lua:"show_parent_pane"`
+	HideEmptyPreviewPane    bool                 `lua:"hide_empty_preview_pane"`
+	Columns                 []string             `lua:"columns"`      /* Valid values defined in ValidColumnValues, an empty list only shows the size */
+	FolderSizes             bool                 `lua:"folder_sizes"` /* Calculate the size of folders in the background, instead of showing their file count */
 }
 
 func NewConfigDefaultValues() Config {
@@ -142,10 +245,15 @@ func NewConfigDefaultValues() Config {
 		SortBy:                  SORT_ALPHABETICAL,
 		FileEventIntervalMillis: 300,
 		ScrollSpeed:             2,
+		GitStatusCacheMegabytes: 64,
 		PreviewSafetyBlocklist:  true,
 		FileSizeFormat:          HUMAN_READABLE,
 		PauseOnOpenFile:         true,
 		FilenameSearchCase:      CASE_INSENSITIVE,
+		SearchCase:              CASE_INSENSITIVE,
+		PaneProportions:         [3]int{1, 3, 3},
+		ShowParentPane:          true,
+		RememberViewSettings:    false,
 	}
 }
 
@@ -210,10 +318,14 @@ func (fen *Fen) Init(path string, app *tview.Application, helpScreenVisible *boo
 	fen.app = app
 	fen.fileOperationsHandler = FileOperationsHandler{fen: fen}
 	fen.folderFileCountCache = make(map[string]int)
+	fen.loadFolderViewSettings()
 
 	fen.gitStatusHandler = GitStatusHandler{app: app, fen: fen}
 	fen.gitStatusHandler.Init()
 
+	fen.folderSizeHandler = FolderSizeHandler{app: app, fen: fen}
+	fen.folderSizeHandler.Init()
+
 	fen.helpScreenVisible = helpScreenVisible
 	fen.librariesScreenVisible = librariesScreenVisible
 	fen.showHomePathAsTilde = true
@@ -223,6 +335,9 @@ func (fen *Fen) Init(path string, app *tview.Application, helpScreenVisible *boo
 	}
 
 	fen.yankSelected = map[string]bool{}
+	fen.history = &History{}
+	fen.navigation = &NavigationStack{}
+	fen.loadNavigationHistory()
 
 	fen.selectedBeforeSelectingWithV = map[string]bool{}
 
@@ -234,10 +349,12 @@ func (fen *Fen) Init(path string, app *tview.Application, helpScreenVisible *boo
 	fen.leftPane = NewFilesPane(fen, LeftPane)
 	fen.middlePane = NewFilesPane(fen, MiddlePane)
 	fen.rightPane = NewFilesPane(fen, RightPane)
+	fen.otherPane = NewFilesPane(fen, MiddlePane)
 
 	fen.leftPane.Init()
 	fen.middlePane.Init()
 	fen.rightPane.Init()
+	fen.otherPane.Init()
 
 	fen.bottomBar = NewBottomBar(fen)
 
@@ -272,6 +389,9 @@ func (fen *Fen) Init(path string, app *tview.Application, helpScreenVisible *boo
 	fen.history.AddToHistory(fen.sel)
 	fen.UpdatePanes(false)
 
+	fen.tabs = []*Tab{{}}
+	fen.currentTab = 0
+
 	return err
 }
 
@@ -279,11 +399,16 @@ func (fen *Fen) Fini() {
 	fen.leftPane.fileWatcher.Close()
 	fen.middlePane.fileWatcher.Close()
 	fen.rightPane.fileWatcher.Close()
+	fen.otherPane.fileWatcher.Close()
 
 	fen.gitStatusHandler.gitIndexFileWatcher.Close()
 
 	close(fen.gitStatusHandler.channel)
 	fen.gitStatusHandler.wg.Wait()
+
+	fen.folderSizeHandler.Fini()
+
+	fen.saveNavigationHistory()
 }
 
 func (fen *Fen) InvalidateFolderFileCountCache() {
@@ -440,7 +565,7 @@ func (fen *Fen) KeepMiddlePaneSelectionInBounds() {
 	if fen.middlePane.selectedEntryIndex >= len(fen.middlePane.entries.Load().([]os.DirEntry)) {
 		if len(fen.middlePane.entries.Load().([]os.DirEntry)) > 0 {
 			fen.sel = fen.middlePane.GetSelectedEntryFromIndex(len(fen.middlePane.entries.Load().([]os.DirEntry)) - 1)
-			err := fen.middlePane.SetSelectedEntryFromString(filepath.Base(fen.sel)) // Duplicated from above...
+			err := fen.middlePane.SetSelectedEntryFromString(fen.middlePane.EntryNameFromPath(fen.sel)) // Duplicated from above...
 			if err != nil {
 				panic("In KeepSelectionInBounds(): " + err.Error())
 			}
@@ -478,10 +603,13 @@ func (fen *Fen) UpdatePanes(forceReadDir bool) {
 	fen.leftPane.SetBorder(fen.config.UiBorders)
 	fen.middlePane.SetBorder(fen.config.UiBorders)
 	fen.rightPane.SetBorder(fen.config.UiBorders)
+	fen.otherPane.SetBorder(fen.config.UiBorders)
 
 	if fen.wd != fen.lastWD {
 		// Has to happen before the filespane ChangeDir() calls which will repopulate the cache
 		fen.InvalidateFolderFileCountCache()
+		fen.VisitFolder(fen.wd)
+		fen.navigation.Visit(fen.wd)
 	}
 	defer func() {
 		fen.lastWD = fen.wd
@@ -496,7 +624,7 @@ func (fen *Fen) UpdatePanes(forceReadDir bool) {
 		fen.leftPane.SetSelectedEntryFromString(filepath.Base(fen.wd))
 	}
 
-	fen.middlePane.SetSelectedEntryFromString(filepath.Base(fen.sel))
+	fen.middlePane.SetSelectedEntryFromString(fen.middlePane.EntryNameFromPath(fen.sel))
 	fen.KeepMiddlePaneSelectionInBounds()
 
 	fen.sel = filepath.Join(fen.wd, fen.middlePane.GetSelectedEntryFromIndex(fen.middlePane.selectedEntryIndex))
@@ -507,7 +635,7 @@ func (fen *Fen) UpdatePanes(forceReadDir bool) {
 		fen.rightPane.parentIsEmptyFolder = false
 	}
 
-	h, err := fen.history.GetHistoryEntryForPath(fen.sel, fen.config.HiddenFiles)
+	h, err := fen.history.GetHistoryEntryForPath(fen.sel, fen.HiddenFilesIn(fen.sel))
 	if err != nil {
 		fen.rightPane.SetSelectedEntryFromIndex(0)
 	} else {
@@ -517,6 +645,13 @@ func (fen *Fen) UpdatePanes(forceReadDir bool) {
 
 	fen.UpdateSelectingWithV()
 
+	if fen.dualPane {
+		fen.updateOtherPane(forceReadDir)
+	}
+
+	// fen.hide_empty_preview_pane depends on what is selected
+	fen.UpdateLayout()
+
 	selStat, selStatErr := os.Lstat(fen.sel)
 	if selStatErr != nil {
 		return
@@ -525,12 +660,14 @@ func (fen *Fen) UpdatePanes(forceReadDir bool) {
 	// Overwrite the cached folder file count for the currently selected folder
 	// If fen.wd changed, we already invalidated the cache so this isn't needed
 	if fen.wd == fen.lastWD && selStat.IsDir() {
-		count, err := FolderFileCount(fen.sel, fen.config.HiddenFiles)
+		count, err := FolderFileCount(fen.sel, fen.HiddenFilesIn(fen.sel))
 		if err == nil {
 			fen.folderFileCountCache[fen.sel] = count
 		}
 	}
 
+	fen.TriggerFolderSizes()
+
 	if !fen.config.GitStatus {
 		return
 	}
@@ -590,18 +727,7 @@ func (fen *Fen) TriggerGitStatus() {
 	}
 
 	if currentRepository != fen.lastInRepository {
-		// Remove previous watched path
-		watchList := fen.gitStatusHandler.gitIndexFileWatcher.WatchList()
-		if watchList == nil {
-			return
-		}
-
-		for _, e := range watchList {
-			fen.gitStatusHandler.gitIndexFileWatcher.Remove(e)
-		}
-
-		// Watch the new path
-		fen.gitStatusHandler.gitIndexFileWatcher.Add(filepath.Join(currentRepository, ".git"))
+		fen.gitStatusHandler.WatchRepository(currentRepository)
 	}
 
 	if err == nil {
@@ -617,12 +743,158 @@ func (fen *Fen) HideFilepanes() {
 	fen.leftPane.Invisible = true
 	fen.middlePane.Invisible = true
 	fen.rightPane.Invisible = true
+	fen.otherPane.Invisible = true
 }
 
 func (fen *Fen) ShowFilepanes() {
 	fen.leftPane.Invisible = false
 	fen.middlePane.Invisible = false
 	fen.rightPane.Invisible = false
+	fen.otherPane.Invisible = false
+}
+
+type layoutItem struct {
+	pane       *FilesPane
+	proportion int
+}
+
+// Returns the panes to show for the current layout, from left to right
+func (fen *Fen) currentLayout() []layoutItem {
+	if fen.dualPane {
+		return []layoutItem{{fen.dualPanePanels[0], 1}, {fen.dualPanePanels[1], 1}}
+	}
+
+	// File list mode makes the middle pane take up the entire width
+	if fen.flattened || fen.middlePaneMaximized {
+		return []layoutItem{{fen.middlePane, 1}}
+	}
+
+	var layout []layoutItem
+	proportions := fen.config.PaneProportions
+	if fen.config.ShowParentPane && proportions[0] > 0 {
+		layout = append(layout, layoutItem{fen.leftPane, proportions[0]})
+	}
+
+	layout = append(layout, layoutItem{fen.middlePane, proportions[1]})
+
+	if proportions[2] > 0 && (!fen.config.HideEmptyPreviewPane || fen.RightPaneHasSomethingToShow()) {
+		layout = append(layout, layoutItem{fen.rightPane, proportions[2]})
+	}
+
+	return layout
+}
+
+// Puts the panes for the current layout in fen.filePanesFlex, if they changed
+func (fen *Fen) UpdateLayout() {
+	if fen.filePanesFlex == nil {
+		return
+	}
+
+	layout := fen.currentLayout()
+	if slices.Equal(layout, fen.layout) {
+		return
+	}
+	fen.layout = layout
+
+	fen.filePanesFlex.Clear()
+	for _, e := range layout {
+		fen.filePanesFlex.AddItem(e.pane, 0, e.proportion, false)
+	}
+}
+
+// Returns false if the right pane would only show "empty" or nothing at all, used for fen.hide_empty_preview_pane
+func (fen *Fen) RightPaneHasSomethingToShow() bool {
+	if len(fen.rightPane.entries.Load().([]os.DirEntry)) > 0 {
+		return true
+	}
+
+	if fen.sel == fen.previewableSel {
+		return fen.previewableSelValue
+	}
+
+	fen.previewableSel = fen.sel
+	fen.previewableSelValue = fen.selIsPreviewable()
+	return fen.previewableSelValue
+}
+
+// Returns true if fen.sel is a file matching one of the fen.preview entries
+func (fen *Fen) selIsPreviewable() bool {
+	stat, err := os.Stat(fen.sel)
+	if err != nil || !stat.Mode().IsRegular() {
+		return false
+	}
+
+	filenameResolved, err := filepath.EvalSymlinks(fen.sel)
+	if err != nil {
+		filenameResolved = fen.sel
+	}
+
+	for _, previewWith := range fen.config.Preview {
+		if PathMatchesList(filenameResolved, previewWith.Match) && !PathMatchesList(filenameResolved, previewWith.DoNotMatch) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Makes the middle pane wider (or narrower if amount is negative) relative to the other panes, used at runtime with the '<' and '>' keys
+func (fen *Fen) ResizeMiddlePane(amount int) {
+	fen.config.PaneProportions[1] = max(1, min(20, fen.config.PaneProportions[1]+amount))
+	fen.UpdateLayout()
+}
+
+func (fen *Fen) ToggleMiddlePaneMaximized() {
+	fen.middlePaneMaximized = !fen.middlePaneMaximized
+	fen.UpdateLayout()
+}
+
+// Only show the entries in folder matching filter in the middle pane, a nil filter removes it
+func (fen *Fen) SetFilter(folder string, filter *SearchMatcher) {
+	if fen.filters == nil {
+		fen.filters = make(map[string]*SearchMatcher)
+	}
+
+	if filter == nil {
+		delete(fen.filters, folder)
+	} else {
+		fen.filters[folder] = filter
+	}
+	fen.rememberFilter(folder, filter)
+
+	// The selection indices no longer match up
+	fen.DisableSelectingWithV()
+}
+
+func (fen *Fen) ToggleFlattened() {
+	fen.flattened = !fen.flattened
+	fen.middlePane.SetFlattened(fen.flattened)
+
+	// The selection indices no longer match up
+	fen.DisableSelectingWithV()
+
+	fen.UpdateLayout()
+}
+
+func (fen *Fen) ToggleChangedFilesOnly() {
+	fen.changedFilesOnly = !fen.changedFilesOnly
+
+	// The selection indices no longer match up
+	fen.DisableSelectingWithV()
+
+	fen.UpdatePanes(true) // The entries that were filtered out have to be read again
+}
+
+// Filters the panes again with the new git status, only used on the main thread after a git status
+func (fen *Fen) refilterChangedFilesOnly() {
+	if !fen.changedFilesOnly {
+		return
+	}
+
+	fen.middlePane.RefilterChangedFilesOnly()
+	fen.rightPane.RefilterChangedFilesOnly()
+	fen.otherPane.RefilterChangedFilesOnly()
+	fen.UpdatePanes(false)
 }
 
 func (fen *Fen) RemoveFromSelectedAndYankSelected(path string) {
@@ -685,7 +957,7 @@ func (fen *Fen) GoRight(app *tview.Application, openWith string) {
 		}*/
 
 	fen.wd = fen.sel
-	fen.sel, err = fen.history.GetHistoryEntryForPath(fen.wd, fen.config.HiddenFiles)
+	fen.sel, err = fen.history.GetHistoryEntryForPath(fen.wd, fen.HiddenFilesIn(fen.wd))
 
 	if err != nil {
 		fen.sel = filepath.Join(fen.wd, fen.rightPane.GetSelectedEntryFromIndex(0))
@@ -913,20 +1185,40 @@ func (fen *Fen) PageDown() {
 	}
 }
 
-func (fen *Fen) GoSearchFirstMatch(searchTerm string) error {
-	if searchTerm == "" {
-		return errors.New("Empty search term")
+// Selects the next (or previous if forward is false) entry in the middle pane matching fen.searchMatcher, wrapping around.
+// If includeSelected is true, the selected entry itself can be the match
+func (fen *Fen) GoSearchMatch(forward, includeSelected bool) error {
+	if fen.searchMatcher == nil {
+		return errors.New("No search term")
+	}
+
+	entries := fen.middlePane.entries.Load().([]os.DirEntry)
+	start := max(0, fen.middlePane.GetSelectedIndexFromEntry(fen.middlePane.EntryNameFromPath(fen.sel)))
+	index, found := NextMatchingIndex(len(entries), start, forward, includeSelected, func(i int) bool {
+		return fen.searchMatcher.Match(entries[i].Name())
+	})
+	if !found {
+		return errors.New("Nothing found")
+	}
+
+	fen.sel = filepath.Join(fen.wd, entries[index].Name())
+	fen.selectingWithVEndIndex = index
+	return nil
+}
+
+// Returns how many entries in the middle pane match fen.searchMatcher
+func (fen *Fen) SearchMatchCount() int {
+	if fen.searchMatcher == nil {
+		return 0
 	}
 
+	count := 0
 	for _, e := range fen.middlePane.entries.Load().([]os.DirEntry) {
-		if strings.Contains(strings.ToLower(e.Name()), strings.ToLower(searchTerm)) {
-			fen.sel = filepath.Join(fen.wd, e.Name())
-			fen.selectingWithVEndIndex = fen.middlePane.GetSelectedIndexFromEntry(e.Name())
-			return nil
+		if fen.searchMatcher.Match(e.Name()) {
+			count++
 		}
 	}
-
-	return errors.New("Nothing found")
+	return count
 }
 
 func (fen *Fen) UpdateSelectingWithV() {
@@ -963,7 +1255,7 @@ func (fen *Fen) GoBookmark(bookmarkNumber int) error {
 		bookmarkNumber--
 	}
 
-	path := fen.config.Bookmarks[bookmarkNumber]
+	path := ExpandBookmarkPath(fen.config.Bookmarks[bookmarkNumber])
 	if path == "" {
 		return errors.New("No path configured for bookmark " + strconv.Itoa(bookmarkNumber+1))
 	}
@@ -1029,7 +1321,7 @@ func (fen *Fen) GoPath(path string) (string, error) {
 
 	if stat.IsDir() {
 		fen.wd = pathToUse
-		h, err := fen.history.GetHistoryEntryForPath(pathToUse, fen.config.HiddenFiles)
+		h, err := fen.history.GetHistoryEntryForPath(pathToUse, fen.HiddenFilesIn(pathToUse))
 		if err != nil {
 			fen.UpdatePanes(false) // Need to do this first so the new selected path is added to history
 			fen.GoTop(true)
@@ -1068,7 +1360,7 @@ func (fen *Fen) GoRootPath() {
 
 // Goes to the path furthest down in the history
 func (fen *Fen) GoRightUpToHistory() {
-	path, err := fen.history.GetHistoryFullPath(fen.sel, fen.config.HiddenFiles)
+	path, err := fen.history.GetHistoryFullPath(fen.sel, fen.HiddenFilesIn(fen.sel))
 	if err != nil {
 		return
 	}
@@ -1090,8 +1382,7 @@ func (fen *Fen) GoRightUpToHistory() {
 
 // This goes to the changed file (any non-folder) closest to the root path of repoPath.
 // If there are multiple candidates, it will select the one with the shortest filepath.
-// If there are some filepaths of equal length, it will choose one randomly.
-// TODO: Implement sorting function in gogitstatus so this is deterministic
+// If there are some filepaths of equal length, it will choose the first one alphabetically.
 func (fen *Fen) GoRightUpToFirstUnstagedOrUntracked(repoPath, currentPath string) error {
 	fen.gitStatusHandler.trackedLocalGitReposMutex.Lock()
 	defer fen.gitStatusHandler.trackedLocalGitReposMutex.Unlock()
@@ -1103,7 +1394,7 @@ func (fen *Fen) GoRightUpToFirstUnstagedOrUntracked(repoPath, currentPath string
 
 	changedFileClosestToRoot := ""
 	shortestPathSeparatorCount := 0
-	for changedFilePath := range gogitstatus.ExcludingDirectories(repo.changedFiles) {
+	for _, changedFilePath := range changedFilesExcludingFolders(repo.changedFiles) {
 		bruhRel, bruhErr := filepath.Rel(repoPath, currentPath)
 		if bruhErr != nil {
 			continue
@@ -1133,6 +1424,87 @@ func (fen *Fen) GoRightUpToFirstUnstagedOrUntracked(repoPath, currentPath string
 	return err
 }
 
+// Goes to the next (or previous) changed file in the local Git repository containing fen.sel, wrapping around.
+// The changed files are in alphabetical order of their paths, so the order stays the same as files are staged.
+// Returns the position of the file, like "3 of 10 changed files"
+func (fen *Fen) GoToChangedFile(next bool) (string, error) {
+	repoPath, err := fen.gitStatusHandler.TryFindParentGitRepository(fen.sel)
+	if err != nil {
+		return "", errors.New("Not in a local Git repository")
+	}
+
+	fen.gitStatusHandler.trackedLocalGitReposMutex.Lock()
+	var changedFiles []string
+	if repo, ok := fen.gitStatusHandler.trackedLocalGitRepos[repoPath]; ok {
+		changedFiles = changedFilesExcludingFolders(repo.changedFiles)
+	}
+	fen.gitStatusHandler.trackedLocalGitReposMutex.Unlock()
+
+	if len(changedFiles) == 0 {
+		return "", errors.New("No changed files in this Git repository")
+	}
+
+	// Compared with slashes, so the files are in the same order as in "git status" on every OS
+	for i, path := range changedFiles {
+		changedFiles[i] = filepath.ToSlash(path)
+	}
+	slices.Sort(changedFiles)
+
+	current, err := filepath.Rel(repoPath, fen.sel)
+	if err != nil {
+		return "", err
+	}
+	current = filepath.ToSlash(current)
+
+	i, found := slices.BinarySearch(changedFiles, current)
+	step := 1
+	if !next {
+		step = -1
+	} else if !found {
+		// i is the position of the file after fen.sel
+		i--
+	}
+
+	// Deleted files can't be gone to
+	for range changedFiles {
+		i = (i + step + len(changedFiles)) % len(changedFiles)
+		path := filepath.Join(repoPath, filepath.FromSlash(changedFiles[i]))
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+
+		_, err := fen.GoPath(path)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(i+1) + " of " + strconv.Itoa(len(changedFiles)) + " changed files", nil
+	}
+
+	return "", errors.New("No changed files in this Git repository")
+}
+
+// Goes to the working directory visited before the current one, like the back button in a web browser
+func (fen *Fen) GoBack() error {
+	folder, ok := fen.navigation.Back()
+	if !ok {
+		return errors.New("No previous folder to go back to")
+	}
+
+	_, err := fen.GoPath(folder)
+	return err
+}
+
+// Goes to the working directory we went back from with Fen.GoBack()
+func (fen *Fen) GoForward() error {
+	folder, ok := fen.navigation.Forward()
+	if !ok {
+		return errors.New("No next folder to go forward to")
+	}
+
+	_, err := fen.GoPath(folder)
+	return err
+}
+
 // Resolves the symlink and uses fen.GoPath() under the hood
 func (fen *Fen) GoSymlink(symlinkPath string) error {
 	// Should not happen
@@ -1150,6 +1522,29 @@ func (fen *Fen) GoSymlink(symlinkPath string) error {
 	return err
 }
 
+// Copies (yankType "copy") or moves (yankType "cut") paths into folder using the file operations handler
+func (fen *Fen) PasteInto(folder string, paths map[string]bool, yankType string) {
+	if yankType == "copy" {
+		for e := range paths {
+			newPath := FilePathUniqueNameIfAlreadyExists(filepath.Join(folder, filepath.Base(e)))
+			go fen.fileOperationsHandler.QueueOperation(FileOperation{operation: Copy, path: e, newPath: newPath})
+		}
+	} else if yankType == "cut" {
+		for e := range paths {
+			newPath := FilePathUniqueNameIfAlreadyExists(filepath.Join(folder, filepath.Base(e)))
+
+			// If we're cutting, then pasting the file to the same location, don't actually do anything
+			if e == filepath.Join(folder, filepath.Base(e)) {
+				continue
+			}
+
+			go fen.fileOperationsHandler.QueueOperation(FileOperation{operation: Rename, path: e, newPath: newPath})
+		}
+	} else {
+		panic("yankType was not \"copy\" or \"cut\"")
+	}
+}
+
 func (fen *Fen) BulkRename(app *tview.Application) error {
 	if fen.config.NoWrite {
 		return errors.New("Can't bulkrename in no-write mode")
@@ -1183,11 +1578,11 @@ func (fen *Fen) BulkRename(app *tview.Application) error {
 			}
 
 			// Only bulkrename selected files in the current working directory
-			if filepath.Dir(entryFullPath) != fen.wd {
+			if !fen.flattened && filepath.Dir(entryFullPath) != fen.wd {
 				panic("In BulkRename(): a selected path was not within fen.wd")
 			}
 
-			basePath := filepath.Base(entryFullPath)
+			basePath := fen.middlePane.EntryNameFromPath(entryFullPath)
 
 			if strings.ContainsRune(basePath, '\n') {
 				return errors.New("A selected path contains a newline, unable to bulkrename")
@@ -1197,11 +1592,11 @@ func (fen *Fen) BulkRename(app *tview.Application) error {
 		}
 	} else {
 		// Only bulkrename selected files in the current working directory
-		if filepath.Dir(fen.sel) != fen.wd {
+		if !fen.flattened && filepath.Dir(fen.sel) != fen.wd {
 			return nil
 		}
 
-		basePath := filepath.Base(fen.sel)
+		basePath := fen.middlePane.EntryNameFromPath(fen.sel)
 
 		if strings.ContainsRune(basePath, '\n') {
 			return errors.New("Path contains a newline, unable to bulkrename")
@@ -1268,7 +1663,8 @@ func (fen *Fen) BulkRename(app *tview.Application) error {
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := scanner.Text()
-		if strings.Contains(line, string(os.PathSeparator)) {
+		// In file list mode, the lines are relative paths which are checked below
+		if !fen.flattened && strings.Contains(line, string(os.PathSeparator)) {
 			return errors.New("Nothing renamed! Because a path contained a path separator \"" + string(os.PathSeparator) + "\"")
 		}
 		postRenameList = append(postRenameList, line)
@@ -1278,6 +1674,15 @@ func (fen *Fen) BulkRename(app *tview.Application) error {
 		return errors.New("Nothing renamed! Wanted " + strconv.Itoa(len(preRenameList)) + " but got " + strconv.Itoa(len(postRenameList)) + " lines")
 	}
 
+	// In file list mode, only allow renaming the file itself and not moving it to another folder
+	if fen.flattened {
+		for i, e := range postRenameList {
+			if filepath.Dir(e) != filepath.Dir(preRenameList[i]) {
+				return errors.New("Nothing renamed! Can't move \"" + preRenameList[i] + "\" to a different folder")
+			}
+		}
+	}
+
 	if reflect.DeepEqual(preRenameList, postRenameList) {
 		// preRenameList equals postRenameList despite sha256 hashsum differing
 		// This can happen due to the file being saved with carriage returns before newlines
@@ -1338,6 +1743,10 @@ func (fen *Fen) BulkRename(app *tview.Application) error {
 	preRenameRandomNames := make([]string, len(preRenameList))
 	for i := range preRenameList {
 		randomName := "fen_" + RandomStringPathSafe(14) // 14 characters (pow(36, 14) combinations), only lowercase letters a-z and 0-9 numbers
+		if fen.flattened {
+			// Keep the file in its own folder
+			randomName = filepath.Join(filepath.Dir(preRenameList[i]), randomName)
+		}
 		_, err := os.Lstat(filepath.Join(fen.wd, randomName))
 		if err == nil {
 			return errors.New("Nothing renamed! Random path \"" + randomName + "\" would've overwritten a file")
@@ -1396,7 +1805,7 @@ func (fen *Fen) BulkRename(app *tview.Application) error {
 
 			// We can't use fen.GoPath() here because it would enter directories
 			fen.sel = preRenameAbs
-			fen.middlePane.SetSelectedEntryFromString(filepath.Base(preRenameAbs)) // fen.UpdatePanes() overwrites fen.sel, so we have to set the index
+			fen.middlePane.SetSelectedEntryFromString(fen.middlePane.EntryNameFromPath(preRenameAbs)) // fen.UpdatePanes() overwrites fen.sel, so we have to set the index
 			fen.history.AddToHistory(preRenameAbs)
 			fen.UpdatePanes(true) // Need to force a read dir so the new entry is in the filespane for fen.GoPath
 
@@ -1418,7 +1827,7 @@ func (fen *Fen) BulkRename(app *tview.Application) error {
 			// We can't use fen.GoPath() here because it would enter directories
 			fen.UpdatePanes(true) // Need to force a read dir so the new entry is in the filespane
 			fen.sel = newNameAbs
-			fen.middlePane.SetSelectedEntryFromString(filepath.Base(newNameAbs)) // fen.UpdatePanes() overwrites fen.sel, so we have to set the index
+			fen.middlePane.SetSelectedEntryFromString(fen.middlePane.EntryNameFromPath(newNameAbs)) // fen.UpdatePanes() overwrites fen.sel, so we have to set the index
 			fen.history.AddToHistory(newNameAbs)
 		}
 		j++
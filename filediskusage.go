@@ -0,0 +1,19 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Returns the space allocated for the file on disk, which can be smaller than its size for sparse or compressed files
+func FileDiskUsage(stat os.FileInfo) uint64 {
+	syscallStat, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		return uint64(stat.Size())
+	}
+
+	// Always in 512-byte units, regardless of the block size of the file system
+	return uint64(syscallStat.Blocks) * 512
+}
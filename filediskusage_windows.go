@@ -0,0 +1,12 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+)
+
+// Unsupported on Windows, returns the file size instead
+func FileDiskUsage(stat os.FileInfo) uint64 {
+	return uint64(stat.Size())
+}
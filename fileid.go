@@ -0,0 +1,24 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Uniquely identifies a file on the system, even when reached through different paths (symlinks, bind mounts)
+type FileID struct {
+	Device uint64
+	Inode  uint64
+}
+
+// Returns false if the file ID could not be determined
+func GetFileID(stat os.FileInfo) (FileID, bool) {
+	syscallStat, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileID{}, false
+	}
+
+	return FileID{Device: uint64(syscallStat.Dev), Inode: uint64(syscallStat.Ino)}, true
+}
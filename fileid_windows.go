@@ -0,0 +1,18 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+)
+
+// Uniquely identifies a file on the system, even when reached through different paths (symlinks, bind mounts)
+type FileID struct {
+	Device uint64
+	Inode  uint64
+}
+
+// Unsupported on Windows, callers should fall back to comparing resolved paths
+func GetFileID(stat os.FileInfo) (FileID, bool) {
+	return FileID{}, false
+}
@@ -0,0 +1,286 @@
+package main
+
+//lint:file-ignore ST1005 some user-visible messages are stored in error values and thus occasionally require capitalization
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Increment this when changing the FilenameIndex or FilenameIndexDirectory structs, so old index files are ignored
+const filenameIndexVersion = 1
+
+// The maximum amount of folders watched for changes while the search filenames popup is open.
+// On Linux, every watched folder uses an inotify watch, and the default limit can be as low as 8192 for the whole user
+const filenameIndexMaxWatchedDirectories = 1000
+
+type FilenameIndexDirectory struct {
+	ModTime int64    // Unix time in nanoseconds
+	Files   []string // Names of the entries which aren't folders, sorted
+	Subdirs []string // Names of the folders, sorted
+}
+
+// An on-disk index of the filenames found recursively in Root, used by the search filenames popup when fen.filename_search_index is set.
+// Since a folders modification time only changes when entries are added, removed or renamed directly inside it,
+// we only need to read the folders which have a different modification time than the last time we looked.
+type FilenameIndex struct {
+	Version     int
+	Root        string
+	HiddenFiles bool
+	Directories map[string]*FilenameIndexDirectory // The keys are paths relative to Root, "" being Root itself
+}
+
+type FilenameIndexRefreshCallbacks struct {
+	FileAdded      func(path string) // Relative to the index Root
+	FileRemoved    func(path string) // Relative to the index Root
+	DirectoryAdded func(path string) // Relative to the index Root, can be nil
+	Cancelled      func() bool       // Can be nil
+}
+
+func NewFilenameIndex(root string, hiddenFiles bool) *FilenameIndex {
+	return &FilenameIndex{
+		Version:     filenameIndexVersion,
+		Root:        root,
+		HiddenFiles: hiddenFiles,
+		Directories: make(map[string]*FilenameIndexDirectory),
+	}
+}
+
+// Returns the path of the index file for root, like "/home/YOUR_USER/.cache/fen/filename-index/1a2b3c4d5e6f7a8b.gob" on Linux
+func FilenameIndexPath(root string, hiddenFiles bool) (string, error) {
+	cacheDir, err := FenCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(root))
+	if hiddenFiles {
+		hash.Write([]byte{1})
+	} else {
+		hash.Write([]byte{0})
+	}
+
+	return filepath.Join(cacheDir, "filename-index", strconv.FormatUint(hash.Sum64(), 16)+".gob"), nil
+}
+
+func LoadFilenameIndex(path, root string, hiddenFiles bool) (*FilenameIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var index FilenameIndex
+	err = gob.NewDecoder(bufio.NewReader(file)).Decode(&index)
+	if err != nil {
+		return nil, err
+	}
+
+	// The hash in the filename could collide, so we also check the root
+	if index.Version != filenameIndexVersion || index.Root != root || index.HiddenFiles != hiddenFiles {
+		return nil, errors.New("Filename index file is outdated or belongs to another folder")
+	}
+
+	if index.Directories == nil {
+		index.Directories = make(map[string]*FilenameIndexDirectory)
+	}
+
+	return &index, nil
+}
+
+// Writes to a temporary file first, so we never leave a half-written index file behind
+func (index *FilenameIndex) Save(path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	err = gob.NewEncoder(writer).Encode(index)
+	if err == nil {
+		err = writer.Flush()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// Calls fn with the path of every indexed file relative to Root, stops early if fn returns false
+func (index *FilenameIndex) ForEachFile(fn func(path string) bool) {
+	var forEach func(relDir string) bool
+	forEach = func(relDir string) bool {
+		dir, ok := index.Directories[relDir]
+		if !ok {
+			return true
+		}
+
+		for _, name := range dir.Files {
+			if !fn(filepath.Join(relDir, name)) {
+				return false
+			}
+		}
+
+		for _, name := range dir.Subdirs {
+			if !forEach(filepath.Join(relDir, name)) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	forEach("")
+}
+
+// Returns the indexed folders relative to Root, the least deeply nested ones first
+func (index *FilenameIndex) DirectoriesByDepth() []string {
+	dirs := make([]string, 0, len(index.Directories))
+	for relDir := range index.Directories {
+		dirs = append(dirs, relDir)
+	}
+
+	slices.SortFunc(dirs, func(a, b string) int {
+		aDepth := strings.Count(a, string(os.PathSeparator))
+		bDepth := strings.Count(b, string(os.PathSeparator))
+		if a == "" {
+			aDepth = -1
+		}
+		if b == "" {
+			bDepth = -1
+		}
+
+		if aDepth != bDepth {
+			return aDepth - bDepth
+		}
+		return strings.Compare(a, b)
+	})
+
+	return dirs
+}
+
+// Brings the index up-to-date for the folder relDir (relative to Root), and reports the changes through callbacks.
+// Folders we haven't seen before are always read recursively.
+// If descendUnchanged is false, we don't look at the subfolders of relDir when relDir itself is unchanged,
+// which is what we want when a file watcher has told us exactly which folder changed.
+func (index *FilenameIndex) Refresh(relDir string, descendUnchanged bool, callbacks FilenameIndexRefreshCallbacks) {
+	if callbacks.Cancelled != nil && callbacks.Cancelled() {
+		return
+	}
+
+	oldDir, existed := index.Directories[relDir]
+
+	stat, err := os.Lstat(filepath.Join(index.Root, relDir))
+	if err != nil || !stat.IsDir() {
+		index.removeDirectory(relDir, callbacks.FileRemoved)
+		return
+	}
+
+	modTime := stat.ModTime().UnixNano()
+	if existed && oldDir.ModTime == modTime {
+		if !descendUnchanged {
+			return
+		}
+
+		for _, name := range oldDir.Subdirs {
+			index.Refresh(filepath.Join(relDir, name), descendUnchanged, callbacks)
+		}
+		return
+	}
+
+	entries, err := os.ReadDir(filepath.Join(index.Root, relDir))
+	if err != nil {
+		index.removeDirectory(relDir, callbacks.FileRemoved)
+		return
+	}
+
+	newDir := &FilenameIndexDirectory{ModTime: modTime}
+	for _, entry := range entries {
+		// Hide files/folders starting with '.' if hidden files are hidden
+		if !index.HiddenFiles && entry.Name()[0] == '.' {
+			continue
+		}
+
+		// Like filepath.WalkDir(), we don't follow symlinked folders
+		if entry.IsDir() {
+			newDir.Subdirs = append(newDir.Subdirs, entry.Name())
+		} else {
+			newDir.Files = append(newDir.Files, entry.Name())
+		}
+	}
+
+	var oldFiles, oldSubdirs []string
+	if existed {
+		oldFiles = oldDir.Files
+		oldSubdirs = oldDir.Subdirs
+	}
+
+	// os.ReadDir() returns the entries sorted by name, so we can use binary search
+	for _, name := range newDir.Files {
+		if _, found := slices.BinarySearch(oldFiles, name); !found {
+			callbacks.FileAdded(filepath.Join(relDir, name))
+		}
+	}
+	for _, name := range oldFiles {
+		if _, found := slices.BinarySearch(newDir.Files, name); !found {
+			callbacks.FileRemoved(filepath.Join(relDir, name))
+		}
+	}
+	for _, name := range oldSubdirs {
+		if _, found := slices.BinarySearch(newDir.Subdirs, name); !found {
+			index.removeDirectory(filepath.Join(relDir, name), callbacks.FileRemoved)
+		}
+	}
+
+	index.Directories[relDir] = newDir
+
+	for _, name := range newDir.Subdirs {
+		subdir := filepath.Join(relDir, name)
+		_, subdirExisted := index.Directories[subdir]
+		if !subdirExisted && callbacks.DirectoryAdded != nil {
+			callbacks.DirectoryAdded(subdir)
+		}
+
+		if descendUnchanged || !subdirExisted {
+			index.Refresh(subdir, descendUnchanged, callbacks)
+		}
+	}
+}
+
+// Removes relDir and all of its subfolders from the index, calling fileRemoved for every file in them
+func (index *FilenameIndex) removeDirectory(relDir string, fileRemoved func(path string)) {
+	dir, ok := index.Directories[relDir]
+	if !ok {
+		return
+	}
+
+	for _, name := range dir.Files {
+		fileRemoved(filepath.Join(relDir, name))
+	}
+
+	for _, name := range dir.Subdirs {
+		index.removeDirectory(filepath.Join(relDir, name), fileRemoved)
+	}
+
+	delete(index.Directories, relDir)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func refreshFilenameIndexForTest(index *FilenameIndex) (added, removed []string) {
+	index.Refresh("", true, FilenameIndexRefreshCallbacks{
+		FileAdded: func(path string) {
+			added = append(added, path)
+		},
+		FileRemoved: func(path string) {
+			removed = append(removed, path)
+		},
+	})
+
+	slices.Sort(added)
+	slices.Sort(removed)
+	return added, removed
+}
+
+func TestFilenameIndexRefresh(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a", filepath.Join("a", "b"), ".hidden"} {
+		err := os.Mkdir(filepath.Join(root, dir), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"1.txt", filepath.Join("a", "2.txt"), filepath.Join("a", "b", "3.txt"), filepath.Join(".hidden", "4.txt")} {
+		err := os.WriteFile(filepath.Join(root, file), nil, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	index := NewFilenameIndex(root, false)
+	added, removed := refreshFilenameIndexForTest(index)
+	expected := []string{"1.txt", filepath.Join("a", "2.txt"), filepath.Join("a", "b", "3.txt")}
+	if !slices.Equal(added, expected) || len(removed) != 0 {
+		t.Fatal("Expected", expected, "to be added, but got added:", added, "removed:", removed)
+	}
+
+	// Nothing changed
+	added, removed = refreshFilenameIndexForTest(index)
+	if len(added) != 0 || len(removed) != 0 {
+		t.Fatal("Expected no changes, but got added:", added, "removed:", removed)
+	}
+
+	err := os.RemoveAll(filepath.Join(root, "a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(root, "a", "5.txt"), nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the modification time changed, even on file systems with a low time resolution
+	future := time.Now().Add(time.Hour)
+	err = os.Chtimes(filepath.Join(root, "a"), future, future)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	added, removed = refreshFilenameIndexForTest(index)
+	if !slices.Equal(added, []string{filepath.Join("a", "5.txt")}) || !slices.Equal(removed, []string{filepath.Join("a", "b", "3.txt")}) {
+		t.Fatal("Unexpected changes, added:", added, "removed:", removed)
+	}
+
+	var files []string
+	index.ForEachFile(func(path string) bool {
+		files = append(files, path)
+		return true
+	})
+	slices.Sort(files)
+	expected = []string{"1.txt", filepath.Join("a", "2.txt"), filepath.Join("a", "5.txt")}
+	if !slices.Equal(files, expected) {
+		t.Fatal("Expected", expected, "but got", files)
+	}
+}
+
+func TestFilenameIndexSaveAndLoad(t *testing.T) {
+	root := t.TempDir()
+	err := os.WriteFile(filepath.Join(root, "file.txt"), nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := NewFilenameIndex(root, false)
+	refreshFilenameIndexForTest(index)
+
+	indexPath := filepath.Join(t.TempDir(), "index.gob")
+	err = index.Save(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadFilenameIndex(indexPath, root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(loaded.Directories[""].Files, []string{"file.txt"}) {
+		t.Fatal("Expected the loaded index to contain file.txt, but got", loaded.Directories[""].Files)
+	}
+
+	_, err = LoadFilenameIndex(indexPath, root, true)
+	if err == nil {
+		t.Fatal("Expected an error when loading an index with different hidden files setting")
+	}
+}
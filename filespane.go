@@ -3,6 +3,7 @@ package main
 //lint:file-ignore ST1005 some user-visible messages are stored in error values and thus occasionally require capitalization
 
 import (
+	"cmp"
 	"errors"
 	"io/fs"
 	"os"
@@ -16,8 +17,10 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/charlievieth/strcase"
 	"github.com/fsnotify/fsnotify"
 	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
 	"github.com/rivo/tview"
 	"github.com/yuin/gopher-lua"
 	"layeh.com/gopher-luar"
@@ -39,6 +42,31 @@ type FilesPane struct {
 
 	lastRenamedPath     string
 	lastRenamedPathTime time.Time
+
+	// File list mode, only used by the middle pane. See Fen.flattened
+	flattened           bool
+	flattenedLoading    bool
+	flattenedGeneration int           // Incremented every time a new walk starts, so entries from a cancelled walk are ignored
+	flattenedCancel     chan struct{} // Closed to stop the current walk
+	flattenedWatched    []string      // The folders added to the file watcher
+
+	// Entries hidden by showing changed files only, keyed by name, so they can be shown again without re-reading the folder.
+	// Only used on the UI thread, see RefilterChangedFilesOnly()
+	changedFilesOnlyHidden       map[string]os.DirEntry
+	changedFilesOnlyHiddenFolder string
+}
+
+// The maximum amount of folders watched for changes in file list mode
+const flattenedMaxWatchedDirectories = 1000
+
+// An entry in file list mode, where Name() is the path relative to the folder of the files pane
+type FlattenedDirEntry struct {
+	fs.DirEntry
+	relativePath string
+}
+
+func (e FlattenedDirEntry) Name() string {
+	return e.relativePath
 }
 
 func NewFilesPane(fen *Fen, panePos PanePos) *FilesPane {
@@ -155,6 +183,8 @@ func (fp *FilesPane) HandleFileEvent(event fsnotify.Event) error {
 		return errors.New("No events")
 	}
 
+	fp.fen.folderSizeHandler.Invalidate(event.Name)
+
 	if event.Has(fsnotify.Create) {
 		// A file temporarily renamed, then renamed back to its old path within 200 milliseconds is added back to the history.
 		// This is a hack to fix navigation because when vim saves a file it temporarily renames the file by appending a tilde (~),
@@ -184,9 +214,31 @@ func (fp *FilesPane) HandleFileEvent(event fsnotify.Event) error {
 	panic("Got invalid file watcher event: " + strconv.Itoa(int(event.Op)))
 }
 
+// Returns the name of the entry for path, which is the path relative to the folder in file list mode
+func (fp *FilesPane) EntryNameFromPath(path string) string {
+	if fp.flattened {
+		rel, err := filepath.Rel(fp.folder, path)
+		if err == nil {
+			return rel
+		}
+	}
+
+	return filepath.Base(path)
+}
+
+func (fp *FilesPane) newEntry(path string, stat os.FileInfo) os.DirEntry {
+	entry := fs.FileInfoToDirEntry(stat)
+	if fp.flattened {
+		return FlattenedDirEntry{DirEntry: entry, relativePath: fp.EntryNameFromPath(path)}
+	}
+
+	return entry
+}
+
 func (fp *FilesPane) AddEntry(path string) error {
+	entryName := fp.EntryNameFromPath(path)
 	alreadyHasEntryByThatName := slices.ContainsFunc(fp.entries.Load().([]os.DirEntry), func(e os.DirEntry) bool {
-		return e.Name() == filepath.Base(path)
+		return e.Name() == entryName
 	})
 	if alreadyHasEntryByThatName {
 		return errors.New("Entry already exists") // Maybe we still want to re-stat the file
@@ -197,16 +249,56 @@ func (fp *FilesPane) AddEntry(path string) error {
 		return err
 	}
 
-	newEntry := fs.FileInfoToDirEntry(stat)
-	fp.entries.Store(append(fp.entries.Load().([]os.DirEntry), newEntry))
+	// File list mode only lists files, but a folder can be moved here with files already inside it.
+	// The walk is started on the UI thread, since that is where the file list mode state is changed
+	if fp.flattened && stat.IsDir() {
+		if fp.fen.HiddenFilesIn(fp.folder) || !strings.HasPrefix(filepath.Base(path), ".") {
+			fp.fen.app.QueueUpdateDraw(func() {
+				fp.walkCreatedFlattenedFolder(path)
+			})
+		}
+		return nil
+	}
+
+	fp.entries.Store(append(fp.entries.Load().([]os.DirEntry), fp.newEntry(path, stat)))
 
 	return nil
 }
 
 func (fp *FilesPane) RemoveEntry(path string) error {
+	entryName := fp.EntryNameFromPath(path)
 	index := slices.IndexFunc(fp.entries.Load().([]os.DirEntry), func(e os.DirEntry) bool {
-		return e.Name() == filepath.Base(path)
+		return e.Name() == entryName
+	})
+
+	// In file list mode, a removed folder removes all the files inside it
+	if fp.flattened {
+		folderPrefix := entryName + string(os.PathSeparator)
+		entriesInFolder := slices.DeleteFunc(slices.Clone(fp.entries.Load().([]os.DirEntry)), func(e os.DirEntry) bool {
+			return strings.HasPrefix(e.Name(), folderPrefix)
+		})
+		if len(entriesInFolder) != len(fp.entries.Load().([]os.DirEntry)) {
+			fp.entries.Store(entriesInFolder)
+			index = slices.IndexFunc(entriesInFolder, func(e os.DirEntry) bool {
+				return e.Name() == entryName
+			})
+		}
+	}
+
+	// It might be hidden by showing changed files only, which is only used on the UI thread
+	folder := fp.folder
+	fp.fen.app.QueueUpdateDraw(func() {
+		if fp.changedFilesOnlyHiddenFolder != folder {
+			return
+		}
+
+		for name := range fp.changedFilesOnlyHidden {
+			if name == entryName || strings.HasPrefix(name, entryName+string(os.PathSeparator)) {
+				delete(fp.changedFilesOnlyHidden, name)
+			}
+		}
 	})
+
 	if index == -1 {
 		return errors.New("Entry not found")
 	}
@@ -221,8 +313,9 @@ func (fp *FilesPane) RemoveEntry(path string) error {
 }
 
 func (fp *FilesPane) UpdateEntry(path string) error {
+	entryName := fp.EntryNameFromPath(path)
 	index := slices.IndexFunc(fp.entries.Load().([]os.DirEntry), func(e os.DirEntry) bool {
-		return e.Name() == filepath.Base(path)
+		return e.Name() == entryName
 	})
 	if index == -1 {
 		return errors.New("Entry not found")
@@ -232,7 +325,7 @@ func (fp *FilesPane) UpdateEntry(path string) error {
 	if err != nil {
 		return err
 	}
-	updatedEntry := fs.FileInfoToDirEntry(stat)
+	updatedEntry := fp.newEntry(path, stat)
 	fp.entries.Store(append(append(fp.entries.Load().([]os.DirEntry)[:index], updatedEntry), fp.entries.Load().([]os.DirEntry)[index+1:]...))
 	return nil
 }
@@ -285,6 +378,11 @@ func (f *FenLuaGlobal) Version() string {
 
 // It might os.ReadDir() even if forceReadDir is false. If forceReadDir is true, it will always os.ReadDir() if path is a folder.
 func (fp *FilesPane) ChangeDir(path string, forceReadDir bool) {
+	if fp.flattened {
+		fp.changeDirFlattened(path, forceReadDir)
+		return
+	}
+
 	stat, err := os.Stat(path)
 	statIsDir := false
 	if err == nil {
@@ -332,11 +430,200 @@ func (fp *FilesPane) ChangeDir(path string, forceReadDir bool) {
 	fp.parentIsEmptyFolder = statIsDir && len(fp.entries.Load().([]os.DirEntry)) <= 0
 }
 
+// Enables or disables file list mode, the entries are re-read on the next ChangeDir()
+func (fp *FilesPane) SetFlattened(flattened bool) {
+	if fp.flattened == flattened {
+		return
+	}
+
+	fp.stopFlattenedWalk()
+	fp.fileWatcher.Remove(fp.folder)
+	fp.flattened = flattened
+	fp.folder = "" // Forces the next ChangeDir() to read the folder
+	fp.entries.Store([]os.DirEntry{})
+}
+
+// Stops watching the folder for file changes and forgets the entries, used when the pane is hidden for good.
+// The next ChangeDir() reads the folder again
+func (fp *FilesPane) StopWatching() {
+	fp.stopFlattenedWalk()
+	fp.fileWatcher.Remove(fp.folder)
+	fp.folder = ""
+	fp.entries.Store([]os.DirEntry{})
+}
+
+// Like ChangeDir(), but lists every file inside path recursively using a background directory walker
+func (fp *FilesPane) changeDirFlattened(path string, forceReadDir bool) {
+	if fp.folder == path && !forceReadDir {
+		fp.parentIsEmptyFolder = false
+		return
+	}
+
+	fp.stopFlattenedWalk()
+	fp.fileWatcher.Remove(fp.folder)
+	fp.folder = path
+	fp.entries.Store([]os.DirEntry{})
+	fp.parentIsEmptyFolder = false
+
+	stat, err := os.Stat(path)
+	if err != nil || !stat.IsDir() {
+		return
+	}
+
+	fp.startFlattenedWalk(path)
+}
+
+// Only call this from the UI thread
+func (fp *FilesPane) stopFlattenedWalk() {
+	if fp.flattenedCancel != nil {
+		close(fp.flattenedCancel)
+		fp.flattenedCancel = nil
+	}
+	fp.flattenedGeneration++
+	fp.flattenedLoading = false
+
+	for _, folder := range fp.flattenedWatched {
+		fp.fileWatcher.Remove(folder)
+	}
+	fp.flattenedWatched = nil
+}
+
+// Only call this from the UI thread, flattenedWatched is not guarded by a mutex
+func (fp *FilesPane) watchFlattenedFolder(path string) {
+	if len(fp.flattenedWatched) >= flattenedMaxWatchedDirectories {
+		return
+	}
+
+	if fp.fileWatcher.Add(path) == nil {
+		fp.flattenedWatched = append(fp.flattenedWatched, path)
+	}
+}
+
+// Streams the files found recursively in root into the entries, updating the screen every 200 milliseconds
+// Only call this from the UI thread
+func (fp *FilesPane) startFlattenedWalk(root string) {
+	fp.flattenedGeneration++
+	fp.flattenedCancel = make(chan struct{}) // Stays open after the walk is done, so folders created later can be walked until file list mode changes folder
+	fp.flattenedLoading = true
+
+	fp.walkFlattenedFolder(root, true)
+}
+
+// Walks a folder created or moved inside the file list mode folder, adding the files already in it.
+// Only call this from the UI thread
+func (fp *FilesPane) walkCreatedFlattenedFolder(path string) {
+	if !fp.flattened || fp.flattenedCancel == nil || !strings.HasPrefix(path, fp.folder+string(os.PathSeparator)) {
+		return
+	}
+
+	fp.walkFlattenedFolder(path, false)
+}
+
+// Walks folder (fp.folder or a folder inside it) in the background, adding the files found to the entries.
+// When isInitialWalk is false, files already in the entries are skipped, since the file watcher might have added them already.
+// Only call this from the UI thread
+func (fp *FilesPane) walkFlattenedFolder(folder string, isInitialWalk bool) {
+	root := fp.folder
+	generation := fp.flattenedGeneration
+	cancel := fp.flattenedCancel
+	hiddenFiles := fp.fen.HiddenFilesIn(root)
+
+	go func() {
+		var newEntries []os.DirEntry
+		var newFolders []string
+		lastUpdateTime := time.Now()
+
+		update := func(done bool) {
+			entriesToAdd := newEntries
+			foldersToWatch := newFolders
+			newEntries = nil
+			newFolders = nil
+
+			fp.fen.app.QueueUpdateDraw(func() {
+				// The walk was cancelled, or a new one started
+				if generation != fp.flattenedGeneration {
+					return
+				}
+
+				entries := fp.entries.Load().([]os.DirEntry)
+				if !isInitialWalk {
+					existingNames := make(map[string]bool, len(entries))
+					for _, e := range entries {
+						existingNames[e.Name()] = true
+					}
+					entriesToAdd = slices.DeleteFunc(entriesToAdd, func(e os.DirEntry) bool {
+						return existingNames[e.Name()]
+					})
+				}
+
+				fp.entries.Store(append(entries, entriesToAdd...))
+				for _, folder := range foldersToWatch {
+					fp.watchFlattenedFolder(folder)
+				}
+				if done && isInitialWalk {
+					fp.flattenedLoading = false
+				}
+
+				fp.FilterAndSortEntries()
+				fp.fen.UpdatePanes(false)
+			})
+		}
+
+		// Unhandled error
+		_ = filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+			select {
+			case <-cancel:
+				return filepath.SkipAll
+			default:
+			}
+
+			if err != nil {
+				return filepath.SkipDir
+			}
+
+			if path == folder {
+				newFolders = append(newFolders, path)
+				return nil
+			}
+
+			// Hide files/folders starting with '.' if hidden files are hidden
+			if !hiddenFiles && d.Name()[0] == '.' {
+				if d.IsDir() {
+					return filepath.SkipDir
+				} else {
+					return nil
+				}
+			}
+
+			if d.IsDir() {
+				newFolders = append(newFolders, path)
+				return nil
+			}
+
+			relativePath, err := filepath.Rel(root, path)
+			if err != nil {
+				return nil
+			}
+
+			newEntries = append(newEntries, FlattenedDirEntry{DirEntry: d, relativePath: relativePath})
+
+			if time.Since(lastUpdateTime) > 200*time.Millisecond {
+				update(false)
+				lastUpdateTime = time.Now()
+			}
+
+			return nil
+		})
+
+		update(true)
+	}()
+}
+
 // When a file event happens in a filespane it only sorts itself, but the parent directory might then have a new modified time and thus need to be sorted.
 // This results in an inconsistency with SORT_MODIFIED
 // FIXME: Create a local copy and update the entries with a mutex, instead of this cursed entries.Load() MULTIPLE places thing...
 func (fp *FilesPane) FilterAndSortEntries() {
-	if !fp.fen.config.HiddenFiles {
+	if !fp.fen.HiddenFilesIn(fp.folder) {
 		withoutHiddenFiles := []os.DirEntry{}
 		for _, e := range fp.entries.Load().([]os.DirEntry) {
 			if !strings.HasPrefix(e.Name(), ".") {
@@ -348,100 +635,233 @@ func (fp *FilesPane) FilterAndSortEntries() {
 		fp.keepSelectionInBounds()
 	}
 
+	// The filter is kept per folder, but we only want to use it in the middle pane
+	filter, hasFilter := fp.fen.filters[fp.folder]
+	if fp.panePos == MiddlePane && hasFilter {
+		fp.entries.Store(slices.DeleteFunc(slices.Clone(fp.entries.Load().([]os.DirEntry)), func(e os.DirEntry) bool {
+			return !filter.Match(e.Name())
+		}))
+		fp.keepSelectionInBounds()
+	}
+
+	// Only changed files and the folders containing them, folders outside of Git repositories aren't filtered
+	if fp.fen.changedFilesOnly && fp.fen.config.GitStatus && fp.panePos != LeftPane {
+		if fp.changedFilesOnlyHidden == nil || fp.changedFilesOnlyHiddenFolder != fp.folder {
+			fp.changedFilesOnlyHidden = make(map[string]os.DirEntry)
+			fp.changedFilesOnlyHiddenFolder = fp.folder
+		}
+
+		repositoryPath, err := fp.fen.gitStatusHandler.TryFindParentGitRepository(fp.folder)
+		if err == nil {
+			fp.entries.Store(slices.DeleteFunc(slices.Clone(fp.entries.Load().([]os.DirEntry)), func(e os.DirEntry) bool {
+				hide := fp.fen.gitStatusHandler.PathGitState(filepath.Join(fp.folder, e.Name()), repositoryPath)&^GIT_IGNORED == 0
+				if hide {
+					fp.changedFilesOnlyHidden[e.Name()] = e
+				}
+				return hide
+			}))
+			fp.keepSelectionInBounds()
+		}
+	} else {
+		fp.changedFilesOnlyHidden = nil
+	}
+
+	sortBy := fp.SortBy()
+
 	// Sort the files as os.ReadDir() would, to guarantee the order
-	if fp.fen.config.SortBy != SORT_NONE {
+	if sortBy != SORT_NONE {
 		// Should be similar enough to https://cs.opensource.google/go/go/+/refs/tags/go1.23.2:src/os/dir.go;l=126
 		slices.SortFunc(fp.entries.Load().([]os.DirEntry), func(a, b fs.DirEntry) int {
 			return strings.Compare(a.Name(), b.Name())
 		})
 	}
 
-	switch fp.fen.config.SortBy {
-	case SORT_ALPHABETICAL: // Since we already sort alphabetically above, we don't need to do anything
-	case SORT_MODIFIED:
-		slices.SortStableFunc(fp.entries.Load().([]os.DirEntry), func(a, b fs.DirEntry) int {
-			aInfo, aErr := a.Info()
-			bInfo, bErr := b.Info()
-			if aErr != nil || bErr != nil {
-				return 0
-			}
+	// SORT_NONE does nothing, this has the side effect of making file events always show up at the bottom, until the entire folder is re-read
+	// Since we already sort alphabetically above, we don't need to do anything for SORT_ALPHABETICAL either
+	if sortBy != SORT_NONE && sortBy != SORT_ALPHABETICAL {
+		fp.sortEntriesBy(fp.entries.Load().([]os.DirEntry), SortByKeys(sortBy))
+	}
 
-			if aInfo.ModTime().Before(bInfo.ModTime()) {
-				return -1
-			}
-			if aInfo.ModTime().Equal(bInfo.ModTime()) {
-				return 0
-			}
+	if sortBy != SORT_NONE && fp.fen.SortReverseIn(fp.folder) {
+		slices.Reverse(fp.entries.Load().([]os.DirEntry))
+	}
 
-			return 1
-		})
-	case SORT_SIZE:
-		slices.SortStableFunc(fp.entries.Load().([]os.DirEntry), func(a, b fs.DirEntry) int {
-			aInfo, aErr := a.Info()
-			bInfo, bErr := b.Info()
-			if aErr != nil || bErr != nil {
-				return 0
-			}
+	if fp.fen.config.FoldersFirst {
+		fp.entries.Store(FoldersAtBeginning(fp.entries.Load().([]os.DirEntry)))
+	}
+}
 
-			// If folder, we consider the folder file count as bytes (though it's kind of messed up with symlinks...)
-			aSize := int(aInfo.Size())
-			if a.IsDir() {
-				aSize, _ = FolderFileCountCached(fp.fen.folderFileCountCache, filepath.Join(fp.folder, a.Name()), fp.fen.config.HiddenFiles)
-			}
+// Filters the entries again after a git status, showing the entries hidden earlier which have changed since.
+// Only call this from the UI thread
+func (fp *FilesPane) RefilterChangedFilesOnly() {
+	if fp.changedFilesOnlyHiddenFolder == fp.folder && len(fp.changedFilesOnlyHidden) > 0 {
+		entries := fp.entries.Load().([]os.DirEntry)
+		shown := make(map[string]bool, len(entries))
+		for _, e := range entries {
+			shown[e.Name()] = true
+		}
 
-			bSize := int(bInfo.Size())
-			if b.IsDir() {
-				bSize, _ = FolderFileCountCached(fp.fen.folderFileCountCache, filepath.Join(fp.folder, b.Name()), fp.fen.config.HiddenFiles)
+		entries = slices.Clone(entries)
+		for name, e := range fp.changedFilesOnlyHidden {
+			if !shown[name] {
+				entries = append(entries, e)
 			}
+		}
+		fp.entries.Store(entries)
+		fp.changedFilesOnlyHidden = nil
+	}
 
-			if aSize < bSize {
-				return -1
-			}
+	fp.FilterAndSortEntries()
+}
 
-			if aSize == bSize {
-				return 0
-			}
-			return 1
-		})
-	case SORT_FILE_EXTENSION:
-		// Also sorts folders based on file extension, kind of weird
-		slices.SortStableFunc(fp.entries.Load().([]os.DirEntry), func(a, b fs.DirEntry) int {
-			aExt := strings.ToLower(filepath.Ext(a.Name()))
-			bExt := strings.ToLower(filepath.Ext(b.Name()))
+// Returns the sorting to use for this folder, see Fen.SortByIn()
+func (fp *FilesPane) SortBy() string {
+	return fp.fen.SortByIn(fp.folder)
+}
 
-			if aExt == bExt {
-				return 0
-			}
+// The values an entry is sorted by. Some of them need a syscall, like the creation time,
+// so they are looked up once per entry before sorting instead of in every comparison
+type entrySortValues struct {
+	infoOk     bool
+	modified   time.Time
+	created    time.Time
+	createdOk  bool
+	accessed   time.Time
+	accessedOk bool
+	size       uint64
+}
+
+// Only looks up the values needed for sortKeys
+func (fp *FilesPane) entrySortValues(entry fs.DirEntry, sortKeys []string) entrySortValues {
+	var values entrySortValues
+	if !slices.ContainsFunc(sortKeys, func(sortKey string) bool {
+		return sortKey == SORT_MODIFIED || sortKey == SORT_CREATED || sortKey == SORT_ACCESSED || sortKey == SORT_SIZE
+	}) {
+		return values
+	}
 
-			if aExt < bExt {
-				return -1
+	info, err := entry.Info()
+	if err != nil {
+		return values
+	}
+	values.infoOk = true
+
+	for _, sortKey := range sortKeys {
+		switch sortKey {
+		case SORT_MODIFIED:
+			values.modified = info.ModTime()
+		case SORT_CREATED:
+			values.created, values.createdOk = FileCreationTime(filepath.Join(fp.folder, entry.Name()), info)
+		case SORT_ACCESSED:
+			values.accessed, values.accessedOk = FileAccessTime(info)
+		case SORT_SIZE:
+			values.size = fp.entrySizeForSorting(entry, info)
+		}
+	}
+
+	return values
+}
+
+// Stable sorts entries in-place by sortKeys, the first sort key that differs decides the order
+func (fp *FilesPane) sortEntriesBy(entries []os.DirEntry, sortKeys []string) {
+	values := make([]entrySortValues, len(entries))
+	indices := make([]int, len(entries))
+	for i, entry := range entries {
+		values[i] = fp.entrySortValues(entry, sortKeys)
+		indices[i] = i
+	}
+
+	slices.SortStableFunc(indices, func(a, b int) int {
+		for _, sortKey := range sortKeys {
+			result := compareEntries(sortKey, entries[a], &values[a], entries[b], &values[b])
+			if result != 0 {
+				return result
 			}
+		}
+		return 0
+	})
 
-			return 1
-		})
+	sorted := make([]os.DirEntry, len(entries))
+	for i, index := range indices {
+		sorted[i] = entries[index]
+	}
+	copy(entries, sorted)
+}
+
+// Compares entries a and b by a single sort key, 0 if they are equal for that key
+func compareEntries(sortKey string, a fs.DirEntry, aValues *entrySortValues, b fs.DirEntry, bValues *entrySortValues) int {
+	switch sortKey {
+	case SORT_ALPHABETICAL:
+		return strings.Compare(a.Name(), b.Name())
+	case SORT_ALPHABETICAL_CASE_INSENSITIVE:
+		return strcase.Compare(a.Name(), b.Name())
+	case SORT_NATURAL:
+		return CompareNatural(a.Name(), b.Name())
+	case SORT_MODIFIED, SORT_CREATED, SORT_ACCESSED:
+		if !aValues.infoOk || !bValues.infoOk {
+			return 0
+		}
+
+		var aTime, bTime time.Time
+		aOk, bOk := true, true
+		switch sortKey {
+		case SORT_MODIFIED:
+			aTime, bTime = aValues.modified, bValues.modified
+		case SORT_CREATED:
+			aTime, aOk = aValues.created, aValues.createdOk
+			bTime, bOk = bValues.created, bValues.createdOk
+		case SORT_ACCESSED:
+			aTime, aOk = aValues.accessed, aValues.accessedOk
+			bTime, bOk = bValues.accessed, bValues.accessedOk
+		}
+
+		if !aOk || !bOk {
+			return 0
+		}
+
+		return aTime.Compare(bTime)
+	case SORT_SIZE:
+		if !aValues.infoOk || !bValues.infoOk {
+			return 0
+		}
+
+		return cmp.Compare(aValues.size, bValues.size)
+	case SORT_FILE_EXTENSION:
+		// Also sorts folders based on file extension, kind of weird
+		aExt := strings.ToLower(filepath.Ext(a.Name()))
+		bExt := strings.ToLower(filepath.Ext(b.Name()))
+		return strings.Compare(aExt, bExt)
 	case SORT_NUMBER:
-		slices.SortStableFunc(fp.entries.Load().([]os.DirEntry), func(a, b fs.DirEntry) int {
-			numberPrefix1 := NumberPrefix(a.Name())
-			numberPrefix2 := NumberPrefix(b.Name())
+		numberPrefix1 := NumberPrefix(a.Name())
+		numberPrefix2 := NumberPrefix(b.Name())
 
-			if numberPrefix1 == "" || numberPrefix2 == "" {
-				return 0
-			}
+		if numberPrefix1 == "" || numberPrefix2 == "" {
+			return 0
+		}
 
-			return CompareNumericalStrings(numberPrefix1, numberPrefix2)
-		})
-	case SORT_NONE: // Does nothing, this has the side effect of making file events always show up at the bottom, until the entire folder is re-read
-	default:
-		panic("Invalid sort_by value \"" + fp.fen.config.SortBy + "\"")
+		return CompareNumericalStrings(numberPrefix1, numberPrefix2)
+	case SORT_TYPE:
+		return cmp.Compare(FileTypeOrder(a.Type()), FileTypeOrder(b.Type()))
 	}
 
-	if fp.fen.config.SortBy != SORT_NONE && fp.fen.config.SortReverse {
-		slices.Reverse(fp.entries.Load().([]os.DirEntry))
+	panic("Invalid sort_by value \"" + sortKey + "\"")
+}
+
+// Folders are sorted by their size with fen.folder_sizes (0 until calculated), otherwise by their file count
+func (fp *FilesPane) entrySizeForSorting(entry fs.DirEntry, info os.FileInfo) uint64 {
+	if !entry.IsDir() {
+		return uint64(info.Size())
 	}
 
-	if fp.fen.config.FoldersFirst {
-		fp.entries.Store(FoldersAtBeginning(fp.entries.Load().([]os.DirEntry)))
+	path := filepath.Join(fp.folder, entry.Name())
+	if fp.fen.config.FolderSizes {
+		size, _ := fp.fen.folderSizeHandler.Cached(path)
+		return size.Apparent
 	}
+
+	// We consider the folder file count as bytes (though it's kind of messed up with symlinks...)
+	count, _ := FolderFileCountCached(fp.fen.folderFileCountCache, path, fp.fen.HiddenFilesIn(path))
+	return uint64(count)
 }
 
 func (fp *FilesPane) keepSelectionInBounds() bool {
@@ -539,6 +959,151 @@ func (fp *FilesPane) GetBottomScreenEntryIndex() int {
 	return bottomScreenEntryIndex
 }
 
+// The columns shown to the right of the filenames, see ValidColumnValues
+func (fp *FilesPane) columns() []string {
+	if fp.panePos != MiddlePane {
+		if fp.fen.config.FileSizeInAllPanes {
+			return []string{COLUMN_SIZE}
+		}
+		return nil
+	}
+
+	if len(fp.fen.config.Columns) == 0 {
+		return []string{COLUMN_SIZE}
+	}
+
+	return fp.fen.config.Columns
+}
+
+func (fp *FilesPane) columnText(column string, entryInfo os.FileInfo, entryFullPath, gitRepoContainingPath string, inGitRepo bool) string {
+	if entryInfo == nil {
+		return "?"
+	}
+
+	switch column {
+	case COLUMN_PERMISSIONS:
+		return FilePermissionsString(entryInfo)
+	case COLUMN_OWNER:
+		username, groupname, err := FileUserAndGroupName(entryInfo)
+		if err != nil {
+			return ""
+		}
+		return username + ":" + groupname
+	case COLUMN_SIZE:
+		if fp.fen.config.FolderSizes && entryInfo.IsDir() {
+			return fp.fen.FolderSizeText(entryFullPath, false)
+		}
+
+		entrySizeText, err := EntrySizeText(fp.fen.folderFileCountCache, entryInfo, entryFullPath, fp.fen.HiddenFilesIn(entryFullPath), fp.fen.config.FileSizeFormat)
+		if err != nil {
+			entrySizeText = "?"
+		}
+
+		if entryInfo.Mode()&os.ModeSymlink != 0 {
+			entrySizeText = "-> " + entrySizeText
+		}
+		return entrySizeText
+	case COLUMN_DISK_USAGE:
+		if entryInfo.IsDir() {
+			if !fp.fen.config.FolderSizes {
+				return "-"
+			}
+			return fp.fen.FolderSizeText(entryFullPath, true)
+		}
+		return BytesToFileSizeFormat(FileDiskUsage(entryInfo), 2, fp.fen.config.FileSizeFormat)
+	case COLUMN_MODIFIED:
+		return entryInfo.ModTime().Format("2006-01-02 15:04")
+	case COLUMN_GIT:
+		if fp.fen.config.GitStatus && inGitRepo {
+			return fp.fen.gitStatusHandler.PathGitState(entryFullPath, gitRepoContainingPath).ShortText()
+		}
+		return "  "
+	case COLUMN_LINK:
+		if entryInfo.Mode()&os.ModeSymlink == 0 {
+			return ""
+		}
+
+		target, err := os.Readlink(entryFullPath)
+		if err != nil {
+			return "-> ?"
+		}
+		return "-> " + target
+	}
+
+	panic("Invalid column: " + column)
+}
+
+// Returns the column texts for each entry shown on screen, padded to line up.
+// Columns are left out from the start of fen.columns until they take up at most half the width.
+// The last one is always shown, but truncated to fit in half the width, so a long symlink target doesn't hide the filename
+func (fp *FilesPane) visibleColumnTexts(scrollOffset, height, width int, gitRepoContainingPath string, inGitRepo bool) [][]string {
+	entries := fp.entries.Load().([]os.DirEntry)
+	entries = entries[min(scrollOffset, len(entries)):min(scrollOffset+height, len(entries))]
+	columns := fp.columns()
+
+	texts := make([][]string, len(entries))
+	columnWidths := make([]int, len(columns))
+	for i, entry := range entries {
+		entryInfo, _ := entry.Info()
+		entryFullPath := filepath.Join(fp.folder, entry.Name())
+		for j, column := range columns {
+			text := fp.columnText(column, entryInfo, entryFullPath, gitRepoContainingPath, inGitRepo)
+			texts[i] = append(texts[i], text)
+			columnWidths[j] = max(columnWidths[j], runewidth.StringWidth(text))
+		}
+	}
+
+	totalWidth := 1 // The space after the last column
+	for _, columnWidth := range columnWidths {
+		totalWidth += columnWidth + 1
+	}
+
+	firstColumn := 0
+	for firstColumn < len(columns)-1 && totalWidth > width/2 {
+		totalWidth -= columnWidths[firstColumn] + 1
+		firstColumn++
+	}
+
+	if len(columns) > 0 && totalWidth > width/2 {
+		lastColumn := len(columns) - 1
+		columnWidths[lastColumn] = max(1, columnWidths[lastColumn]-(totalWidth-width/2))
+		for i := range texts {
+			texts[i][lastColumn] = runewidth.Truncate(texts[i][lastColumn], columnWidths[lastColumn], string(missingSpaceRune))
+		}
+	}
+
+	for i := range texts {
+		texts[i] = texts[i][firstColumn:]
+		for j := range texts[i] {
+			column := columns[firstColumn+j]
+			padding := strings.Repeat(" ", max(0, columnWidths[firstColumn+j]-runewidth.StringWidth(texts[i][j])))
+
+			// The sizes are right-aligned, so the numbers line up
+			if column == COLUMN_SIZE || column == COLUMN_DISK_USAGE {
+				texts[i][j] = padding + texts[i][j]
+			} else {
+				texts[i][j] += padding
+			}
+		}
+	}
+
+	return texts
+}
+
+// Returns true if this is the panel which isn't focused in dual-pane mode
+func (fp *FilesPane) isInactivePanel() bool {
+	return fp.fen.dualPane && fp == fp.fen.otherPane
+}
+
+// The selected paths to show, the inactive panel in dual-pane mode shows the selection of its own tab
+func (fp *FilesPane) selectedPaths() map[string]bool {
+	if fp.isInactivePanel() {
+		return fp.fen.otherTab.selected
+	}
+
+	return fp.fen.selected
+}
+
 func (fp *FilesPane) CanOpenFile(path string) bool {
 	// We let the Go garbage collector close the file, because manually calling .Close() on it can be really slow, atleast on Linux
 	// It seems to only get up to about 7 duplicate file descriptors for a single path at a time
@@ -570,8 +1135,15 @@ func (fp *FilesPane) Draw(screen tcell.Screen) {
 		fp.Box.DrawForSubclass(screen, fp)
 
 		if gitRepoErr == nil {
-			// TODO: Show current branch name, maybe show remote name?
-			tview.Print(screen, filepath.Base(gitRepo), x+1, y-1, w-1, tview.AlignLeft, tcell.ColorBlue)
+			// TODO: Maybe show remote name?
+			title := tview.Escape(filepath.Base(gitRepo))
+			if fp.fen.config.GitStatus {
+				branch := fp.fen.gitStatusHandler.RepositoryInfo(gitRepo).Branch
+				if branch != "" {
+					title += " (" + tview.Escape(branch) + ")"
+				}
+			}
+			tview.Print(screen, title, x+1, y-1, w-1, tview.AlignLeft, tcell.ColorBlue)
 		}
 	}
 
@@ -579,13 +1151,76 @@ func (fp *FilesPane) Draw(screen tcell.Screen) {
 		w++
 	}
 
+	if fp.flattened && fp.flattenedLoading && len(fp.entries.Load().([]os.DirEntry)) <= 0 {
+		tview.Print(screen, "[::d]loading...", x, y, w, tview.AlignLeft, tcell.ColorDefault)
+		return
+	}
+
+	_, hasFilter := fp.fen.filters[fp.folder]
+	if fp.panePos == MiddlePane && hasFilter && len(fp.entries.Load().([]os.DirEntry)) <= 0 {
+		tview.Print(screen, "[:red]no matches", x, y, w, tview.AlignLeft, tcell.ColorDefault)
+		return
+	}
+
 	if fp.panePos == RightPane && fp.parentIsEmptyFolder || ((fp.panePos != RightPane) && len(fp.entries.Load().([]os.DirEntry)) <= 0) && fp.folder != filepath.Dir(fp.folder) {
 		tview.Print(screen, "[:red]empty", x, y, w, tview.AlignLeft, tcell.ColorDefault)
 		return
 	}
 
-	// File previews
 	stat, statErr := os.Stat(fp.fen.sel)
+
+	// Git diff preview, files without changes are previewed as usual
+	if fp.panePos == RightPane && fp.fen.gitPreview == GIT_PREVIEW_DIFF && statErr == nil && stat.Mode().IsRegular() && len(fp.entries.Load().([]os.DirEntry)) <= 0 {
+		diff, err := fp.fen.GitDiffPreview(fp.fen.sel)
+		if err != nil {
+			tview.Print(screen, "Git diff failed:", x, y, w-1, tview.AlignLeft, tcell.ColorRed)
+			for i, line := range tview.WordWrap(err.Error(), w-1) {
+				tview.Print(screen, tview.Escape(line), x, y+1+i, w-1, tview.AlignLeft, tcell.ColorDefault)
+			}
+			return
+		}
+
+		if len(diff) > 0 {
+			textView := tview.NewTextView()
+			textView.Box.SetRect(x, y, w-1, h)
+			textView.SetBackgroundColor(tcell.ColorDefault)
+			textView.SetTextColor(tcell.ColorDefault)
+			tview.ANSIWriter(textView).Write(diff)
+			textView.Draw(screen)
+			return
+		}
+	}
+
+	// Git log and blame previews, files without commits are previewed as usual
+	if fp.panePos == RightPane && (fp.fen.gitPreview == GIT_PREVIEW_LOG || fp.fen.gitPreview == GIT_PREVIEW_BLAME) && statErr == nil && stat.Mode().IsRegular() && len(fp.entries.Load().([]os.DirEntry)) <= 0 {
+		lines, loading, err := fp.fen.GitHistoryPreview(fp.fen.sel)
+		if loading {
+			tview.Print(screen, "[::d]loading...", x, y, w, tview.AlignLeft, tcell.ColorDefault)
+			return
+		}
+
+		if err != nil {
+			failedText := "Git log failed:"
+			if fp.fen.gitPreview == GIT_PREVIEW_BLAME {
+				failedText = "Git blame failed:"
+			}
+
+			tview.Print(screen, failedText, x, y, w-1, tview.AlignLeft, tcell.ColorRed)
+			for i, line := range tview.WordWrap(err.Error(), w-1) {
+				tview.Print(screen, tview.Escape(line), x, y+1+i, w-1, tview.AlignLeft, tcell.ColorDefault)
+			}
+			return
+		}
+
+		if len(lines) > 0 {
+			for i, line := range lines[:min(len(lines), h)] {
+				tview.Print(screen, line, x, y+i, w-1, tview.AlignLeft, tcell.ColorDefault)
+			}
+			return
+		}
+	}
+
+	// File previews
 	if fp.panePos == RightPane && len(fp.fen.config.Preview) > 0 && statErr == nil && stat.Mode().IsRegular() && fp.CanOpenFile(fp.fen.sel) && len(fp.entries.Load().([]os.DirEntry)) <= 0 {
 		w--
 
@@ -688,6 +1323,8 @@ func (fp *FilesPane) Draw(screen tcell.Screen) {
 	}
 
 	scrollOffset := fp.GetTopScreenEntryIndex()
+	columnTexts := fp.visibleColumnTexts(scrollOffset, h, w, gitRepoContainingPath, repoErr == nil)
+
 	for i, entry := range fp.entries.Load().([]os.DirEntry)[scrollOffset:] {
 		// We don't draw at the bottom row of the screen, since it's occupied by the bottomBar
 		if i >= h {
@@ -701,21 +1338,28 @@ func (fp *FilesPane) Draw(screen tcell.Screen) {
 
 		spaceForSelected := ""
 		if i+scrollOffset == fp.selectedEntryIndex {
-			style = style.Reverse(true)
+			if fp.isInactivePanel() {
+				style = style.Underline(true)
+			} else {
+				style = style.Reverse(true)
+			}
 		}
 
-		_, selected := fp.fen.selected[entryFullPath]
+		_, selected := fp.selectedPaths()[entryFullPath]
 
 		if selected {
 			spaceForSelected = " "
 			style = style.Foreground(tcell.ColorYellow)
 			style = style.Bold(false) // FileColor() makes folders and executables bold
 		} else {
-			// Show unstaged/untracked files in red
+			// Show changed files in colors distinct from filetype colors, see GitFileState.Color()
 			if fp.fen.config.GitStatus && repoErr == nil {
-				if fp.fen.gitStatusHandler.PathIsUnstagedOrUntracked(entryFullPath, gitRepoContainingPath) {
-					// Same color used in the git status command
-					style = style.Foreground(tcell.ColorMaroon).Bold(false) // Unstaged/untracked file in a git directory, distinct from filetype colors
+				gitState := fp.fen.gitStatusHandler.PathGitState(entryFullPath, gitRepoContainingPath)
+				color, ok := gitState.Color()
+				if ok {
+					style = style.Foreground(color).Bold(gitState&GIT_CONFLICTED != 0)
+				} else if gitState&GIT_IGNORED != 0 {
+					style = style.Dim(true)
 				}
 			}
 		}
@@ -734,23 +1378,17 @@ func (fp *FilesPane) Draw(screen tcell.Screen) {
 		//styleStr := StyleToStyleTagString(style)
 
 		entrySizePrintedSize := 0
-		if fp.fen.config.FileSizeInAllPanes || fp.panePos == MiddlePane {
-			entrySizeText, err := EntrySizeText(fp.fen.folderFileCountCache, entryInfo, entryFullPath, fp.fen.config.HiddenFiles, fp.fen.config.FileSizeFormat)
-			if err != nil {
-				entrySizeText = "?"
-			}
-
-			if entryInfo != nil && entryInfo.Mode()&os.ModeSymlink != 0 {
-				entrySizeText = "-> " + entrySizeText
+		// The entries can change while drawing
+		if i < len(columnTexts) && len(columnTexts[i]) > 0 {
+			entryColumnsText := " " + strings.Join(columnTexts[i], " ") + " "
+			entrySizePrintedSize = runewidth.StringWidth(entryColumnsText)
+
+			columnX := x + w - entrySizePrintedSize - 1
+			for _, c := range entryColumnsText {
+				screen.SetContent(columnX, y+i, c, nil, style)
+				columnX += runewidth.RuneWidth(c)
 			}
 
-			entrySizeText = " " + entrySizeText + " "
-			for j := 0; j < len(entrySizeText); j++ {
-				screen.SetContent(x+w-len(entrySizeText)+j-1, y+i, rune(entrySizeText[j]), nil, style)
-			}
-
-			entrySizePrintedSize = len(entrySizeText)
-
 			//_, entrySizePrintedSize = tview.Print(screen, styleStr+"[:default] "+tview.Escape(entrySizeText)+" ", x, y+i, w-1, tview.AlignRight, tcell.ColorDefault)
 		}
 
@@ -764,7 +1402,11 @@ func (fp *FilesPane) Draw(screen tcell.Screen) {
 		}
 		screen.SetContent(xToUse, y+i, ' ', nil, style)
 		xToUse++
-		leftSizePrinted := PrintFilenameInvisibleCharactersAsCodeHighlighted(screen, xToUse, y+i, w-1-entrySizePrintedSize+widthOffset, entry.Name(), style)
+		var matchRanges [][]int
+		if fp.panePos == MiddlePane && fp.fen.searchMatcher != nil {
+			matchRanges = fp.fen.searchMatcher.MatchRanges(entry.Name())
+		}
+		leftSizePrinted := PrintFilenameInvisibleCharactersAsCodeHighlighted(screen, xToUse, y+i, w-1-entrySizePrintedSize+widthOffset, entry.Name(), style, matchRanges)
 
 		for j := 0; j < w-1-leftSizePrinted-entrySizePrintedSize-(xToUse-x); j++ {
 			screen.SetContent(xToUse+leftSizePrinted+j, y+i, ' ', nil, style)
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/fsnotify/fsnotify"
@@ -50,3 +53,123 @@ func TestAddEventToBatch(t *testing.T) {
 		}
 	}
 }
+
+func TestEntryNameFromPath(t *testing.T) {
+	fp := FilesPane{folder: "/home/user"}
+	if got := fp.EntryNameFromPath("/home/user/folder/file.txt"); got != "file.txt" {
+		t.Fatalf("Expected \"file.txt\", but got %q", got)
+	}
+
+	fp.flattened = true
+	if got := fp.EntryNameFromPath("/home/user/folder/file.txt"); got != "folder/file.txt" {
+		t.Fatalf("Expected \"folder/file.txt\", but got %q", got)
+	}
+}
+
+func TestVisibleColumnTexts(t *testing.T) {
+	folder := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		err := os.WriteFile(filepath.Join(folder, name), []byte("hello"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fen := &Fen{config: NewConfigDefaultValues(), folderFileCountCache: map[string]int{}}
+	fen.config.FileSizeFormat = BYTES
+	fen.config.Columns = []string{COLUMN_MODIFIED, COLUMN_SIZE}
+	fp := FilesPane{fen: fen, panePos: MiddlePane, folder: folder}
+	fp.entries.Store(entries)
+
+	texts := fp.visibleColumnTexts(0, 10, 100, "", false)
+	if len(texts) != 2 || len(texts[0]) != 2 || texts[0][1] != "5 B" {
+		t.Fatalf("Expected 2 columns with the size last, but got %q", texts)
+	}
+
+	// The modified time doesn't fit in half of 20 cells
+	texts = fp.visibleColumnTexts(0, 10, 20, "", false)
+	if len(texts[0]) != 1 || texts[0][0] != "5 B" {
+		t.Fatalf("Expected only the size column, but got %q", texts)
+	}
+
+	fp.panePos = LeftPane
+	texts = fp.visibleColumnTexts(0, 10, 100, "", false)
+	if len(texts[0]) != 0 {
+		t.Fatalf("Expected no columns in the left pane, but got %q", texts)
+	}
+
+	// A long symlink target is truncated to half of the width
+	err = os.Symlink(strings.Repeat("x", 50), filepath.Join(folder, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err = os.ReadDir(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp.entries.Store(entries)
+	fp.panePos = MiddlePane
+	fen.config.Columns = []string{COLUMN_LINK}
+
+	texts = fp.visibleColumnTexts(0, 10, 20, "", false)
+	if len(texts) != 3 || texts[2][0] != "-> xxxx"+string(missingSpaceRune) {
+		t.Fatalf("Expected the symlink target to be truncated, but got %q", texts)
+	}
+}
+
+func TestChangedFilesOnly(t *testing.T) {
+	folder := t.TempDir()
+	for _, name := range []string{".git", "folder", "unchanged folder"} {
+		err := os.Mkdir(filepath.Join(folder, name), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"changed", "unchanged", filepath.Join("folder", "new")} {
+		err := os.WriteFile(filepath.Join(folder, name), nil, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fen := &Fen{config: NewConfigDefaultValues(), changedFilesOnly: true}
+	fen.config.GitStatus = true
+	fen.gitStatusHandler.trackedLocalGitRepos = map[string]*ChangedFileState{
+		folder: {changedFiles: includingFolderStates(map[string]GitFileState{"changed": GIT_MODIFIED, filepath.Join("folder", "new"): GIT_UNTRACKED})},
+	}
+
+	fp := FilesPane{fen: fen, panePos: MiddlePane, folder: folder}
+	fp.entries.Store(entries)
+	fp.FilterAndSortEntries()
+
+	var names []string
+	for _, e := range fp.entries.Load().([]os.DirEntry) {
+		names = append(names, e.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"folder", "changed"}) {
+		t.Fatalf("Expected only the changed file and the folder containing a changed file, but got %q", names)
+	}
+
+	// A file changed since is shown again after the next git status, without reading the folder
+	fen.gitStatusHandler.trackedLocalGitRepos[folder].changedFiles["unchanged"] = GIT_MODIFIED
+	os.Remove(filepath.Join(folder, "unchanged"))
+	fp.RefilterChangedFilesOnly()
+
+	names = nil
+	for _, e := range fp.entries.Load().([]os.DirEntry) {
+		names = append(names, e.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"folder", "changed", "unchanged"}) {
+		t.Fatalf("Expected the file changed since to be shown, but got %q", names)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build darwin || freebsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Returns false if the access time could not be determined
+func FileAccessTime(stat os.FileInfo) (time.Time, bool) {
+	syscallStat, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(syscallStat.Atimespec.Sec), int64(syscallStat.Atimespec.Nsec)), true
+}
+
+// Returns false if the creation time could not be determined
+func FileCreationTime(path string, stat os.FileInfo) (time.Time, bool) {
+	syscallStat, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(syscallStat.Birthtimespec.Sec), int64(syscallStat.Birthtimespec.Nsec)), true
+}
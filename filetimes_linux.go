@@ -0,0 +1,32 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Returns false if the access time could not be determined
+func FileAccessTime(stat os.FileInfo) (time.Time, bool) {
+	syscallStat, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(syscallStat.Atim.Sec), int64(syscallStat.Atim.Nsec)), true
+}
+
+// Returns false if the creation time could not be determined, not all Linux file systems store it
+func FileCreationTime(path string, stat os.FileInfo) (time.Time, bool) {
+	var statx unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, unix.STATX_BTIME, &statx)
+	if err != nil || statx.Mask&unix.STATX_BTIME == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(statx.Btime.Sec, int64(statx.Btime.Nsec)), true
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// Unsupported on this operating system
+func FileAccessTime(stat os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Unsupported on this operating system
+func FileCreationTime(path string, stat os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Returns false if the access time could not be determined
+func FileAccessTime(stat os.FileInfo) (time.Time, bool) {
+	attributes, ok := stat.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, attributes.LastAccessTime.Nanoseconds()), true
+}
+
+// Returns false if the creation time could not be determined
+func FileCreationTime(path string, stat os.FileInfo) (time.Time, bool) {
+	attributes, ok := stat.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, attributes.CreationTime.Nanoseconds()), true
+}
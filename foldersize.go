@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rivo/tview"
+)
+
+type FolderSize struct {
+	Apparent uint64 // The sum of the file sizes, like "du --apparent-size"
+	OnDisk   uint64 // The space allocated on disk, like "du"
+}
+
+// Calculates the size of folders in the background when fen.folder_sizes is true, see Request()
+type FolderSizeHandler struct {
+	app *tview.Application
+	fen *Fen
+
+	requested chan struct{} // Wakes up the worker when pending has changed
+
+	mutex                  sync.Mutex
+	sizes                  map[string]FolderSize
+	pending                []string // Calculated in order, the first ones are shown first
+	calculating            string   // The folder being calculated, empty if none
+	calculatingInvalidated bool     // A file event happened inside the folder being calculated, so the result is outdated
+	cancelFunc             context.CancelFunc
+	stopped                bool
+}
+
+// Remembering the size of more folders than this is not worth the memory, so we start over
+const folderSizeCacheMaxEntries = 10000
+
+func (fsh *FolderSizeHandler) Init() {
+	if fsh.app == nil {
+		panic("In FolderSizeHandler Init(), app was nil")
+	}
+
+	fsh.sizes = make(map[string]FolderSize)
+	fsh.requested = make(chan struct{}, 1)
+
+	go func() {
+		for range fsh.requested {
+			for {
+				folder, ctx, ok := fsh.startNext()
+				if !ok {
+					break
+				}
+
+				size, err := CalculateFolderSize(ctx, folder, fsh.Cached)
+				if fsh.finish(folder, size, err) {
+					fsh.app.QueueUpdateDraw(func() {
+						fsh.fen.folderSizeCalculated(folder)
+					})
+				}
+			}
+		}
+	}()
+}
+
+// Stops the calculation.
+// We don't wait for the worker to exit, it might be stuck in a QueueUpdateDraw() that never runs after the app has stopped
+func (fsh *FolderSizeHandler) Fini() {
+	fsh.mutex.Lock()
+	fsh.stopped = true
+	if fsh.cancelFunc != nil {
+		fsh.cancelFunc()
+	}
+	fsh.mutex.Unlock()
+
+	close(fsh.requested)
+}
+
+// Replaces the folders waiting to be calculated.
+// The folder currently being calculated is cancelled, unless it is one of folders
+func (fsh *FolderSizeHandler) Request(folders []string) {
+	fsh.mutex.Lock()
+	if fsh.stopped {
+		fsh.mutex.Unlock()
+		return
+	}
+
+	fsh.pending = slices.DeleteFunc(slices.Clone(folders), func(folder string) bool {
+		_, cached := fsh.sizes[folder]
+		return cached || folder == fsh.calculating
+	})
+
+	if fsh.calculating != "" && !slices.Contains(folders, fsh.calculating) {
+		fsh.cancelFunc()
+	}
+	fsh.mutex.Unlock()
+
+	// Don't block if the worker has already been woken up
+	select {
+	case fsh.requested <- struct{}{}:
+	default:
+	}
+}
+
+// Returns false if the size of folder hasn't been calculated
+func (fsh *FolderSizeHandler) Cached(folder string) (FolderSize, bool) {
+	fsh.mutex.Lock()
+	defer fsh.mutex.Unlock()
+
+	size, ok := fsh.sizes[folder]
+	return size, ok
+}
+
+// Returns true if folder is waiting to be, or being calculated
+func (fsh *FolderSizeHandler) IsCalculating(folder string) bool {
+	fsh.mutex.Lock()
+	defer fsh.mutex.Unlock()
+
+	return folder == fsh.calculating || slices.Contains(fsh.pending, folder)
+}
+
+// Forgets the size of every folder containing path, and of path itself if it's a folder.
+// Used on file events, since the folders have changed size
+func (fsh *FolderSizeHandler) Invalidate(path string) {
+	if !filepath.IsAbs(path) {
+		return
+	}
+
+	fsh.mutex.Lock()
+	defer fsh.mutex.Unlock()
+
+	parents := SplitPath(path)
+	for _, parent := range parents {
+		delete(fsh.sizes, parent)
+	}
+
+	// A removed or renamed folder takes its subfolders with it
+	for folder := range fsh.sizes {
+		if strings.HasPrefix(folder, path+string(os.PathSeparator)) {
+			delete(fsh.sizes, folder)
+		}
+	}
+
+	if slices.Contains(parents, fsh.calculating) {
+		fsh.calculatingInvalidated = true
+	}
+}
+
+// Remembers a folder size calculated elsewhere, like in the disk usage screen
+func (fsh *FolderSizeHandler) Store(folder string, size FolderSize) {
+	fsh.mutex.Lock()
+	defer fsh.mutex.Unlock()
+
+	if len(fsh.sizes) >= folderSizeCacheMaxEntries {
+		fsh.sizes = make(map[string]FolderSize)
+	}
+	fsh.sizes[folder] = size
+}
+
+func (fsh *FolderSizeHandler) InvalidateAll() {
+	fsh.mutex.Lock()
+	defer fsh.mutex.Unlock()
+
+	fsh.sizes = make(map[string]FolderSize)
+	if fsh.calculating != "" {
+		fsh.calculatingInvalidated = true
+	}
+}
+
+// Returns the next pending folder to calculate, or false if there are none left
+func (fsh *FolderSizeHandler) startNext() (string, context.Context, bool) {
+	fsh.mutex.Lock()
+	defer fsh.mutex.Unlock()
+
+	for len(fsh.pending) > 0 {
+		folder := fsh.pending[0]
+		fsh.pending = fsh.pending[1:]
+
+		if _, cached := fsh.sizes[folder]; cached {
+			continue
+		}
+
+		var ctx context.Context
+		ctx, fsh.cancelFunc = context.WithCancel(context.Background())
+		fsh.calculating = folder
+		fsh.calculatingInvalidated = false
+		return folder, ctx, true
+	}
+
+	return "", nil, false
+}
+
+// Stores the calculated size, returns false if it was cancelled, failed or outdated
+func (fsh *FolderSizeHandler) finish(folder string, size FolderSize, err error) bool {
+	fsh.mutex.Lock()
+	defer fsh.mutex.Unlock()
+
+	fsh.cancelFunc()
+	fsh.calculating = ""
+
+	if err != nil || fsh.stopped {
+		return false
+	}
+
+	// Calculate it again, the folder is no longer in pending so a new Request() wouldn't
+	if fsh.calculatingInvalidated {
+		fsh.pending = append([]string{folder}, fsh.pending...)
+		return false
+	}
+
+	if len(fsh.sizes) >= folderSizeCacheMaxEntries {
+		fsh.sizes = make(map[string]FolderSize)
+	}
+	fsh.sizes[folder] = size
+	return true
+}
+
+// Walks folder like "du -x", not counting other mounted file systems or following symlinks.
+// Unreadable files and folders are skipped, and unlike "du", hard links are counted once for every path.
+// cached is used to skip subfolders that have already been calculated
+func CalculateFolderSize(ctx context.Context, folder string, cached func(folder string) (FolderSize, bool)) (FolderSize, error) {
+	var size FolderSize
+
+	rootStat, err := os.Lstat(folder)
+	if err != nil {
+		return size, err
+	}
+	rootID, hasRootID := GetFileID(rootStat)
+
+	err = filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() && path != folder {
+			subfolderSize, ok := cached(path)
+			if ok {
+				size.Apparent += subfolderSize.Apparent
+				size.OnDisk += subfolderSize.OnDisk
+				return filepath.SkipDir
+			}
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() && hasRootID {
+			id, ok := GetFileID(info)
+			if ok && id.Device != rootID.Device {
+				return filepath.SkipDir
+			}
+		}
+
+		size.Apparent += uint64(info.Size())
+		size.OnDisk += FileDiskUsage(info)
+		return nil
+	})
+
+	return size, err
+}
+
+// Returns the folders shown in the panes, in the order they should be calculated
+func (fen *Fen) visibleFolders() []string {
+	var folders []string
+	addFolders := func(fp *FilesPane) {
+		for _, entry := range fp.entries.Load().([]os.DirEntry) {
+			if entry.IsDir() {
+				folders = append(folders, filepath.Join(fp.folder, entry.Name()))
+			}
+		}
+	}
+
+	// The selected folder first, and the current folder after its subfolders so it can use their sizes
+	selStat, err := os.Lstat(fen.sel)
+	if err == nil && selStat.IsDir() {
+		folders = append(folders, fen.sel)
+	}
+	addFolders(fen.middlePane)
+	folders = append(folders, fen.wd)
+
+	if fen.dualPane {
+		addFolders(fen.otherPane)
+	}
+
+	if fen.config.FileSizeInAllPanes {
+		addFolders(fen.rightPane)
+		addFolders(fen.leftPane)
+	}
+
+	return folders
+}
+
+// Ask the folder size handler to calculate the size of the folders shown, if fen.folder_sizes is true
+func (fen *Fen) TriggerFolderSizes() {
+	if !fen.config.FolderSizes {
+		return
+	}
+
+	fen.folderSizeHandler.Request(fen.visibleFolders())
+}
+
+// Called on the main thread when the size of folder has been calculated
+func (fen *Fen) folderSizeCalculated(folder string) {
+	// Folders sorted by size have to be sorted again
+	resorted := false
+	for _, fp := range []*FilesPane{fen.leftPane, fen.middlePane, fen.rightPane, fen.otherPane} {
+		if fp.folder != filepath.Dir(folder) || !slices.Contains(SortByKeys(fp.SortBy()), SORT_SIZE) {
+			continue
+		}
+
+		fp.FilterAndSortEntries()
+		resorted = true
+	}
+
+	if resorted {
+		fen.UpdatePanes(false)
+	}
+}
+
+// Returns the size text of a folder when fen.folder_sizes is true, "..." while calculating and "?" if it failed
+func (fen *Fen) FolderSizeText(folder string, onDisk bool) string {
+	size, ok := fen.folderSizeHandler.Cached(folder)
+	if !ok {
+		if fen.folderSizeHandler.IsCalculating(folder) {
+			return "..."
+		}
+		return "?"
+	}
+
+	if onDisk {
+		return BytesToFileSizeFormat(size.OnDisk, 2, fen.config.FileSizeFormat)
+	}
+	return BytesToFileSizeFormat(size.Apparent, 2, fen.config.FileSizeFormat)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCalculateFolderSize(t *testing.T) {
+	folder := t.TempDir()
+	os.MkdirAll(filepath.Join(folder, "sub", "deeper"), 0o755)
+	os.WriteFile(filepath.Join(folder, "a"), make([]byte, 1000), 0o644)
+	os.WriteFile(filepath.Join(folder, "sub", "deeper", "b"), make([]byte, 234), 0o644)
+
+	noCache := func(string) (FolderSize, bool) { return FolderSize{}, false }
+
+	folderStat, _ := os.Lstat(folder)
+	subStat, _ := os.Lstat(filepath.Join(folder, "sub"))
+	deeperStat, _ := os.Lstat(filepath.Join(folder, "sub", "deeper"))
+	foldersApparent := uint64(folderStat.Size() + subStat.Size() + deeperStat.Size())
+
+	size, err := CalculateFolderSize(context.Background(), folder, noCache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size.Apparent != foldersApparent+1234 {
+		t.Fatal("Expected an apparent size of", foldersApparent+1234, "but got", size.Apparent)
+	}
+
+	// Cached subfolders are not walked
+	cachedSub := func(path string) (FolderSize, bool) {
+		if path == filepath.Join(folder, "sub") {
+			return FolderSize{Apparent: 5, OnDisk: 7}, true
+		}
+		return FolderSize{}, false
+	}
+	size, _ = CalculateFolderSize(context.Background(), folder, cachedSub)
+	if size.Apparent != uint64(folderStat.Size())+1000+5 {
+		t.Fatal("Expected the cached size of the subfolder to be used, but got", size.Apparent)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = CalculateFolderSize(ctx, folder, noCache)
+	if err == nil {
+		t.Fatal("Expected an error when cancelled")
+	}
+}
+
+func TestFolderSizeHandlerInvalidate(t *testing.T) {
+	fsh := FolderSizeHandler{sizes: map[string]FolderSize{
+		"/a":       {},
+		"/a/b":     {},
+		"/a/b/c":   {},
+		"/a/other": {},
+		"/ab":      {},
+	}}
+
+	fsh.Invalidate("/a/b/file")
+	for _, folder := range []string{"/a", "/a/b"} {
+		if _, ok := fsh.Cached(folder); ok {
+			t.Fatal("Expected", folder, "to be invalidated")
+		}
+	}
+	for _, folder := range []string{"/a/b/c", "/a/other", "/ab"} {
+		if _, ok := fsh.Cached(folder); !ok {
+			t.Fatal("Expected", folder, "to still be cached")
+		}
+	}
+
+	// Removing a folder forgets its subfolders
+	fsh.Invalidate("/a/b")
+	if _, ok := fsh.Cached("/a/b/c"); ok {
+		t.Fatal("Expected /a/b/c to be invalidated")
+	}
+}
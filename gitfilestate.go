@@ -0,0 +1,568 @@
+package main
+
+//lint:file-ignore ST1005 some user-visible messages are stored in error values and thus occasionally require capitalization
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"hash/fnv"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/kivattt/gogitstatus"
+)
+
+// What has changed about a file in a local git repository, folders have the states of the files inside them combined
+type GitFileState uint16
+
+const (
+	GIT_STAGED_ADDED    GitFileState = 1 << iota // New file added to the index
+	GIT_STAGED_MODIFIED                          // Changes added to the index
+	GIT_STAGED_DELETED                           // Removal added to the index
+	GIT_STAGED_RENAMED                           // Renamed or copied in the index
+	GIT_MODIFIED                                 // Changes not added to the index
+	GIT_DELETED                                  // Removed, but not from the index
+	GIT_UNTRACKED
+	GIT_IGNORED
+	GIT_CONFLICTED // Unmerged, both sides changed it in a merge
+)
+
+const GIT_STAGED = GIT_STAGED_ADDED | GIT_STAGED_MODIFIED | GIT_STAGED_DELETED | GIT_STAGED_RENAMED
+const GIT_UNSTAGED = GIT_MODIFIED | GIT_DELETED
+
+// Returns the 2-letter status like in "git status --short", the first letter is the index and the second is the work tree.
+// Returns 2 spaces for unchanged files
+func (state GitFileState) ShortText() string {
+	if state&GIT_CONFLICTED != 0 {
+		return "UU"
+	}
+
+	if state&(GIT_STAGED|GIT_UNSTAGED) == 0 {
+		if state&GIT_UNTRACKED != 0 {
+			return "??"
+		}
+		if state&GIT_IGNORED != 0 {
+			return "!!"
+		}
+		return "  "
+	}
+
+	index := " "
+	switch {
+	case state&GIT_STAGED_ADDED != 0:
+		index = "A"
+	case state&GIT_STAGED_RENAMED != 0:
+		index = "R"
+	case state&GIT_STAGED_MODIFIED != 0:
+		index = "M"
+	case state&GIT_STAGED_DELETED != 0:
+		index = "D"
+	}
+
+	workTree := " "
+	switch {
+	case state&GIT_MODIFIED != 0:
+		workTree = "M"
+	case state&GIT_DELETED != 0:
+		workTree = "D"
+	case state&GIT_UNTRACKED != 0: // Only for folders, which can contain both
+		workTree = "?"
+	}
+
+	return index + workTree
+}
+
+// Returns the color used for filenames with this state, or false to keep the filetype color
+func (state GitFileState) Color() (tcell.Color, bool) {
+	switch {
+	case state&GIT_CONFLICTED != 0:
+		return tcell.ColorRed, true
+	case state&GIT_UNSTAGED != 0:
+		return tcell.ColorMaroon, true // Same color used in the git status command
+	case state&GIT_UNTRACKED != 0:
+		return tcell.ColorOlive, true
+	case state&GIT_STAGED != 0:
+		return tcell.ColorGreen, true // Same color used in the git status command
+	}
+
+	return 0, false
+}
+
+// Converts the 2-letter XY status of "git status --porcelain" to a GitFileState
+func gitPorcelainState(x, y byte) GitFileState {
+	switch string([]byte{x, y}) {
+	case "??":
+		return GIT_UNTRACKED
+	case "!!":
+		return GIT_IGNORED
+	case "DD", "AU", "UD", "UA", "DU", "AA", "UU":
+		return GIT_CONFLICTED
+	}
+
+	var state GitFileState
+	switch x {
+	case 'A':
+		state |= GIT_STAGED_ADDED
+	case 'M', 'T':
+		state |= GIT_STAGED_MODIFIED
+	case 'D':
+		state |= GIT_STAGED_DELETED
+	case 'R', 'C':
+		state |= GIT_STAGED_RENAMED
+	}
+
+	switch y {
+	case 'M', 'T':
+		state |= GIT_MODIFIED
+	case 'D':
+		state |= GIT_DELETED
+	}
+
+	return state
+}
+
+// Parses the output of "git status --porcelain=v1 -z", returning the state of each path relative to the repository
+func ParseGitStatusPorcelain(output []byte) map[string]GitFileState {
+	states := make(map[string]GitFileState)
+
+	fields := bytes.Split(output, []byte{0})
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if len(field) < 4 || field[2] != ' ' {
+			continue
+		}
+
+		x, y := field[0], field[1]
+		path := filepath.Clean(filepath.FromSlash(strings.TrimSuffix(string(field[3:]), "/")))
+		states[path] |= gitPorcelainState(x, y)
+
+		// Renames and copies are followed by the original path, which we skip
+		if x == 'R' || x == 'C' || y == 'R' || y == 'C' {
+			i++
+		}
+	}
+
+	return states
+}
+
+// Adds the states of every file to the folders containing it, except for ignored files.
+// Ignored folders keep their own state, so they're shown as ignored. Modifies and returns states
+func includingFolderStates(states map[string]GitFileState) map[string]GitFileState {
+	files := make([]string, 0, len(states))
+	for path := range states {
+		files = append(files, path)
+	}
+
+	for _, path := range files {
+		state := states[path] &^ GIT_IGNORED
+		if state == 0 {
+			continue
+		}
+
+		for parent := filepath.Dir(path); parent != "."; parent = filepath.Dir(parent) {
+			states[parent] |= state
+		}
+	}
+
+	return states
+}
+
+// Returns the state of every changed, untracked and ignored path in the repository at repositoryPath, including folders.
+// Reads the index and the HEAD commit to tell staged changes apart, and uses gogitstatus for the unstaged and untracked files.
+// If git is installed, it's used to list the ignored files, otherwise they aren't shown.
+// Falls back to "git status" for indexes we can't read
+func GitStatus(ctx context.Context, repositoryPath string) (map[string]GitFileState, error) {
+	gitDir, err := GitDir(repositoryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bare repositories have no files to show the state of
+	if gitDir == repositoryPath {
+		return nil, errors.New("Bare repository")
+	}
+
+	states, entries, err := gitStatusFromIndex(ctx, repositoryPath, gitDir)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		porcelainStates, porcelainErr := gitStatusPorcelain(ctx, repositoryPath)
+		if porcelainErr != nil {
+			return nil, err
+		}
+		return includingFolderStates(porcelainStates), nil
+	}
+
+	ignored, err := gitIgnoredPaths(ctx, repositoryPath, gitDir, entries, states)
+	if err == nil {
+		for _, path := range ignored {
+			states[path] |= GIT_IGNORED
+		}
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return includingFolderStates(states), nil
+}
+
+// Runs "git status" in the repository at repositoryPath, returns an error if git isn't installed
+func gitStatusPorcelain(ctx context.Context, repositoryPath string) (map[string]GitFileState, error) {
+	// With --ignored=matching, ignored folders are listed as a single entry, and not every file inside them
+	cmd := exec.CommandContext(ctx, "git", "-C", repositoryPath, "status", "--porcelain=v1", "-z", "--ignored=matching", "--untracked-files=all")
+	// Without this, git would refresh the index file, which the git status handler watches for changes
+	cmd.Env = append(os.Environ(), "GIT_OPTIONAL_LOCKS=0")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseGitStatusPorcelain(output), nil
+}
+
+// What a git status remembers for the next one in the same repository, since reading the HEAD tree and listing the ignored folders are slow in big repositories
+type gitStatusRepositoryCache struct {
+	headCommit string
+	headTree   string
+	headFiles  map[string]gitTreeFile // Not modified after it's made, so it can be used outside of the mutex
+
+	ignoredStamp uint64
+	ignored      []string
+	ignoredKnown bool
+
+	lastUsed time.Time
+}
+
+// Only the most recently used repositories are remembered, since the HEAD tree of a big repository uses a lot of memory
+const gitStatusRepositoryCacheMax = 8
+
+var (
+	gitStatusRepositoryCaches      = make(map[string]*gitStatusRepositoryCache) // The keys are repository paths
+	gitStatusRepositoryCachesMutex sync.Mutex                                   // Also for the fields of the caches
+)
+
+// Returns the cache of the repository at repositoryPath, forgetting the least recently used one if there are too many.
+// Only call this with gitStatusRepositoryCachesMutex locked
+func gitStatusRepositoryCacheFor(repositoryPath string) *gitStatusRepositoryCache {
+	cache, ok := gitStatusRepositoryCaches[repositoryPath]
+	if !ok {
+		if len(gitStatusRepositoryCaches) >= gitStatusRepositoryCacheMax {
+			oldestPath := ""
+			for path, c := range gitStatusRepositoryCaches {
+				if oldestPath == "" || c.lastUsed.Before(gitStatusRepositoryCaches[oldestPath].lastUsed) {
+					oldestPath = path
+				}
+			}
+			delete(gitStatusRepositoryCaches, oldestPath)
+		}
+
+		cache = &gitStatusRepositoryCache{}
+		gitStatusRepositoryCaches[repositoryPath] = cache
+	}
+
+	cache.lastUsed = time.Now()
+	return cache
+}
+
+// Returns every file in the HEAD commit of the repository at repositoryPath, keyed by their slash-separated path.
+// The files are only read again when HEAD points to another tree. Returns no files if there are no commits yet
+func gitHeadFiles(ctx context.Context, repositoryPath, gitDir string) (map[string]gitTreeFile, error) {
+	head, err := ResolveGitRef(gitDir, "HEAD")
+	if err != nil {
+		return map[string]gitTreeFile{}, nil
+	}
+
+	gitStatusRepositoryCachesMutex.Lock()
+	cache := gitStatusRepositoryCacheFor(repositoryPath)
+	if cache.headFiles != nil && cache.headCommit == head {
+		files := cache.headFiles
+		gitStatusRepositoryCachesMutex.Unlock()
+		return files, nil
+	}
+	gitStatusRepositoryCachesMutex.Unlock()
+
+	objects := NewGitObjectReader(GitCommonDir(gitDir))
+	defer objects.Close()
+
+	commit, err := objects.ReadCommit(head)
+	if err != nil {
+		return nil, err
+	}
+
+	// A new commit can have the same tree, like after "git commit --amend" only changing the message
+	gitStatusRepositoryCachesMutex.Lock()
+	if cache.headFiles != nil && cache.headTree == commit.tree {
+		cache.headCommit = head
+		files := cache.headFiles
+		gitStatusRepositoryCachesMutex.Unlock()
+		return files, nil
+	}
+	gitStatusRepositoryCachesMutex.Unlock()
+
+	files := make(map[string]gitTreeFile)
+	err = objects.TreeFiles(ctx, commit.tree, "", files)
+	if err != nil {
+		return nil, err
+	}
+
+	gitStatusRepositoryCachesMutex.Lock()
+	cache.headCommit = head
+	cache.headTree = commit.tree
+	cache.headFiles = files
+	gitStatusRepositoryCachesMutex.Unlock()
+
+	return files, nil
+}
+
+// Returns the ignored files and folders in the repository at repositoryPath, folders are listed without the files inside them.
+// Git has to look through every folder for these, so it's only run again when a folder or a file with ignore patterns has changed, see gitIgnoredStamp().
+// Returns an error if git isn't installed
+func gitIgnoredPaths(ctx context.Context, repositoryPath, gitDir string, entries []gitIndexEntry, states map[string]GitFileState) ([]string, error) {
+	stamp := gitIgnoredStamp(repositoryPath, gitDir, entries, states)
+
+	gitStatusRepositoryCachesMutex.Lock()
+	cache := gitStatusRepositoryCacheFor(repositoryPath)
+	if cache.ignoredKnown && cache.ignoredStamp == stamp {
+		ignored := cache.ignored
+		gitStatusRepositoryCachesMutex.Unlock()
+		return ignored, nil
+	}
+	gitStatusRepositoryCachesMutex.Unlock()
+
+	cmd := exec.CommandContext(ctx, "git", "-C", repositoryPath, "ls-files", "-z", "--others", "--ignored", "--exclude-standard", "--directory")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var ignored []string
+	for _, path := range bytes.Split(output, []byte{0}) {
+		if len(path) > 0 {
+			ignored = append(ignored, filepath.Clean(filepath.FromSlash(string(path))))
+		}
+	}
+
+	gitStatusRepositoryCachesMutex.Lock()
+	cache.ignoredStamp = stamp
+	cache.ignored = ignored
+	cache.ignoredKnown = true
+	gitStatusRepositoryCachesMutex.Unlock()
+
+	return ignored, nil
+}
+
+// Returns a hash of the modification times of the folders in the repository at repositoryPath, and of the files with ignore patterns.
+// Adding or removing a file or folder changes the modification time of the folder containing it, so the hash changes when an ignored file or folder is added.
+// Only the folders containing tracked or untracked files are known, ignored folders are already listed as a whole
+func gitIgnoredStamp(repositoryPath, gitDir string, entries []gitIndexEntry, states map[string]GitFileState) uint64 {
+	folders := map[string]bool{".": true}
+	ignoreFiles := []string{filepath.Join(GitCommonDir(gitDir), "info", "exclude")}
+
+	// The default global ignore file, we don't read core.excludesFile from the git config
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		ignoreFiles = append(ignoreFiles, filepath.Join(configHome, "git", "ignore"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		ignoreFiles = append(ignoreFiles, filepath.Join(home, ".config", "git", "ignore"))
+	}
+
+	addPath := func(path string) {
+		if filepath.Base(path) == ".gitignore" {
+			ignoreFiles = append(ignoreFiles, filepath.Join(repositoryPath, path))
+		}
+
+		// The parents of a known folder are already known
+		for folder := filepath.Dir(path); !folders[folder]; folder = filepath.Dir(folder) {
+			folders[folder] = true
+		}
+	}
+
+	for _, entry := range entries {
+		addPath(filepath.FromSlash(entry.path))
+	}
+	for path := range states {
+		addPath(path)
+	}
+
+	sortedFolders := make([]string, 0, len(folders))
+	for folder := range folders {
+		sortedFolders = append(sortedFolders, filepath.Join(repositoryPath, folder))
+	}
+	slices.Sort(sortedFolders)
+
+	hash := fnv.New64a()
+	for _, path := range append(sortedFolders, ignoreFiles...) {
+		hash.Write([]byte(path))
+		stat, err := os.Stat(path)
+		if err == nil {
+			hash.Write([]byte("\x00" + strconv.FormatInt(stat.ModTime().UnixNano(), 10) + "\x00" + strconv.FormatInt(stat.Size(), 10)))
+		}
+		hash.Write([]byte{0})
+	}
+
+	return hash.Sum64()
+}
+
+// Returns the state of every changed and untracked file in the repository at repositoryPath, without the git command and without ignored files.
+// Also returns the entries of the index.
+// The staged changes are found by comparing the index to the HEAD commit, and the unstaged ones by comparing the files to the index with gogitstatus
+func gitStatusFromIndex(ctx context.Context, repositoryPath, gitDir string) (map[string]GitFileState, []gitIndexEntry, error) {
+	// The index file is in the git directory, which isn't the ".git" folder for worktrees and submodules
+	indexPath := filepath.Join(gitDir, "index")
+	entries, err := readGitIndex(indexPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changedFiles, err := gogitstatus.StatusRaw(ctx, repositoryPath, indexPath, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headFiles, err := gitHeadFiles(ctx, repositoryPath, gitDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A submodule is a single entry in the index, gogitstatus would list the files inside it as untracked
+	submodules := make(map[string]string)
+	for _, entry := range entries {
+		if entry.mode&gitModeTypeMask == gitModeGitlink && entry.stage == 0 {
+			submodules[filepath.FromSlash(entry.path)] = entry.hash
+		}
+	}
+
+	insideSubmodule := func(path string) bool {
+		for parent := filepath.Dir(path); parent != "."; parent = filepath.Dir(parent) {
+			if _, ok := submodules[parent]; ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	states := make(map[string]GitFileState, len(changedFiles))
+	for path, changedFile := range changedFiles {
+		if insideSubmodule(path) {
+			continue
+		}
+
+		if changedFile.Untracked {
+			states[path] = GIT_UNTRACKED
+		} else if changedFile.WhatChanged&gogitstatus.DELETED != 0 {
+			states[path] = GIT_DELETED
+		} else {
+			states[path] = GIT_MODIFIED
+		}
+	}
+
+	for path, hash := range submodules {
+		if gitSubmoduleChanged(ctx, filepath.Join(repositoryPath, path), hash) {
+			states[path] |= GIT_MODIFIED
+		}
+	}
+
+	var added []gitIndexEntry
+	inIndex := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		inIndex[entry.path] = true
+		path := filepath.FromSlash(entry.path)
+
+		if entry.stage > 0 {
+			states[path] = GIT_CONFLICTED
+			continue
+		}
+
+		headFile, inHead := headFiles[entry.path]
+		if !inHead {
+			states[path] |= GIT_STAGED_ADDED
+			added = append(added, entry)
+		} else if headFile.hash != entry.hash || headFile.mode != entry.mode {
+			states[path] |= GIT_STAGED_MODIFIED
+		}
+	}
+
+	var deleted []string
+	for path := range headFiles {
+		if !inIndex[path] {
+			deleted = append(deleted, path)
+		}
+	}
+	slices.Sort(deleted)
+
+	deletedByHash := make(map[string][]string)
+	for _, path := range deleted {
+		states[filepath.FromSlash(path)] |= GIT_STAGED_DELETED
+		deletedByHash[headFiles[path].hash] = append(deletedByHash[headFiles[path].hash], path)
+	}
+
+	// Like git, a deleted file with the same content as an added one was renamed.
+	// Only the new path is shown as renamed, like in "git status"
+	for _, entry := range added {
+		candidates := deletedByHash[entry.hash]
+		if len(candidates) == 0 {
+			continue
+		}
+		deletedByHash[entry.hash] = candidates[1:]
+
+		newPath := filepath.FromSlash(entry.path)
+		states[newPath] = states[newPath]&^GIT_STAGED_ADDED | GIT_STAGED_RENAMED
+
+		oldPath := filepath.FromSlash(candidates[0])
+		states[oldPath] &^= GIT_STAGED_DELETED
+		if states[oldPath] == 0 {
+			delete(states, oldPath)
+		}
+	}
+
+	return states, entries, nil
+}
+
+// Returns true if the submodule at submodulePath is at another commit than hash, or has changed or untracked files.
+// Returns false if it isn't checked out
+func gitSubmoduleChanged(ctx context.Context, submodulePath, hash string) bool {
+	gitDir, err := GitDir(submodulePath)
+	if err != nil || gitDir == submodulePath {
+		return false
+	}
+
+	head, err := ResolveGitRef(gitDir, "HEAD")
+	if err == nil && head != hash {
+		return true
+	}
+
+	states, _, err := gitStatusFromIndex(ctx, submodulePath, gitDir)
+	return err == nil && len(states) > 0
+}
+
+// Returns the changed and untracked files in states sorted alphabetically, leaving out the folders containing them and ignored files
+func changedFilesExcludingFolders(states map[string]GitFileState) []string {
+	folders := make(map[string]bool)
+	for path, state := range states {
+		if state&^GIT_IGNORED == 0 {
+			continue
+		}
+
+		for parent := filepath.Dir(path); parent != "."; parent = filepath.Dir(parent) {
+			folders[parent] = true
+		}
+	}
+
+	var files []string
+	for path, state := range states {
+		if state&^GIT_IGNORED != 0 && !folders[path] {
+			files = append(files, path)
+		}
+	}
+
+	slices.Sort(files)
+	return files
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseGitStatusPorcelain(t *testing.T) {
+	output := "M  staged.go\x00 M modified.go\x00MM both.go\x00A  dir/added.go\x00R  new.go\x00old.go\x00 D deleted.go\x00?? dir/sub/untracked.txt\x00!! build/\x00UU conflict.go\x00"
+
+	expected := map[string]GitFileState{
+		"staged.go":                      GIT_STAGED_MODIFIED,
+		"modified.go":                    GIT_MODIFIED,
+		"both.go":                        GIT_STAGED_MODIFIED | GIT_MODIFIED,
+		filepath.Join("dir", "added.go"): GIT_STAGED_ADDED,
+		"new.go":                         GIT_STAGED_RENAMED,
+		"deleted.go":                     GIT_DELETED,
+		filepath.Join("dir", "sub", "untracked.txt"): GIT_UNTRACKED,
+		"build":       GIT_IGNORED,
+		"conflict.go": GIT_CONFLICTED,
+	}
+
+	got := ParseGitStatusPorcelain([]byte(output))
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %v, but got %v", expected, got)
+	}
+
+	got = includingFolderStates(got)
+	if got["dir"] != GIT_STAGED_ADDED|GIT_UNTRACKED {
+		t.Fatalf("Expected the folder to combine the states of its files, but got %v", got["dir"])
+	}
+	if got[filepath.Join("dir", "sub")] != GIT_UNTRACKED {
+		t.Fatalf("Expected the subfolder to be untracked, but got %v", got[filepath.Join("dir", "sub")])
+	}
+
+	files := changedFilesExcludingFolders(got)
+	expectedFiles := []string{"both.go", "conflict.go", "deleted.go", filepath.Join("dir", "added.go"), filepath.Join("dir", "sub", "untracked.txt"), "modified.go", "new.go", "staged.go"}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Fatalf("Expected %v, but got %v", expectedFiles, files)
+	}
+}
+
+func TestGitFileStateShortText(t *testing.T) {
+	expectedResults := map[GitFileState]string{
+		0:                                  "  ",
+		GIT_STAGED_MODIFIED:                "M ",
+		GIT_MODIFIED:                       " M",
+		GIT_STAGED_ADDED | GIT_MODIFIED:    "AM",
+		GIT_UNTRACKED:                      "??",
+		GIT_IGNORED:                        "!!",
+		GIT_CONFLICTED | GIT_MODIFIED:      "UU",
+		GIT_STAGED_ADDED | GIT_UNTRACKED:   "A?",
+		GIT_STAGED_DELETED | GIT_UNTRACKED: "D?",
+		GIT_STAGED_RENAMED | GIT_DELETED:   "RD",
+	}
+
+	for state, expected := range expectedResults {
+		got := state.ShortText()
+		if got != expected {
+			t.Fatalf("Expected \"" + expected + "\", but got \"" + got + "\"")
+		}
+	}
+}
+
+func TestGitStatus(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repositoryPath := t.TempDir()
+	testGit(t, repositoryPath, "init", "--quiet")
+	testGitCommit(t, repositoryPath, "modified")
+	testGitCommit(t, repositoryPath, "renamed")
+	testGitCommit(t, repositoryPath, "deleted")
+
+	write := func(filename, content string) {
+		os.MkdirAll(filepath.Dir(filepath.Join(repositoryPath, filename)), 0o755)
+		err := os.WriteFile(filepath.Join(repositoryPath, filename), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("modified", "staged")
+	testGit(t, repositoryPath, "add", "modified")
+	write("modified", "staged and then modified")
+	os.Mkdir(filepath.Join(repositoryPath, "dir"), 0o755)
+	testGit(t, repositoryPath, "mv", "renamed", "dir/new")
+	testGit(t, repositoryPath, "rm", "--quiet", "--cached", "deleted")
+	write("added", "added")
+	testGit(t, repositoryPath, "add", "added")
+	write(".gitignore", "build/\n")
+	write("build/output", "output")
+
+	expectedSlash := map[string]GitFileState{
+		"modified":   GIT_STAGED_MODIFIED | GIT_MODIFIED,
+		"dir/new":    GIT_STAGED_RENAMED,
+		"dir":        GIT_STAGED_RENAMED,
+		"deleted":    GIT_STAGED_DELETED | GIT_UNTRACKED,
+		"added":      GIT_STAGED_ADDED,
+		".gitignore": GIT_UNTRACKED,
+		"build":      GIT_IGNORED,
+	}
+	expected := make(map[string]GitFileState)
+	for path, state := range expectedSlash {
+		expected[filepath.FromSlash(path)] = state
+	}
+
+	for _, indexVersion := range []string{"2", "3", "4"} {
+		testGit(t, repositoryPath, "update-index", "--index-version", indexVersion)
+
+		states, err := GitStatus(context.Background(), repositoryPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(states, expected) {
+			t.Fatal("Index version", indexVersion+": Expected", expected, "but got", states)
+		}
+	}
+
+	// The remembered HEAD tree and ignored folders are read again after a commit and a new ignored folder
+	testGit(t, repositoryPath, "commit", "--quiet", "-m", "staged")
+	write(".gitignore", "build/\nout/\n")
+	write("out/output", "output")
+
+	states, err := GitStatus(context.Background(), repositoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]GitFileState{
+		"modified":   GIT_MODIFIED,
+		"deleted":    GIT_UNTRACKED,
+		".gitignore": GIT_UNTRACKED,
+		"build":      GIT_IGNORED,
+		"out":        GIT_IGNORED,
+	}
+	if !reflect.DeepEqual(states, expected) {
+		t.Fatal("After committing, expected", expected, "but got", states)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+//lint:file-ignore ST1005 some user-visible messages are stored in error values and thus occasionally require capitalization
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+)
+
+// An entry in the git index file, which has every file as it will be in the next commit
+type gitIndexEntry struct {
+	path  string // Slash-separated, relative to the repository
+	mode  uint32
+	hash  string // Hex hash of the blob, or the commit of a submodule
+	stage int    // 0, or 1 to 3 for the base, ours and theirs sides of a merge conflict
+}
+
+// Reads the entries of the git index file at indexPath, versions 2 to 4 are supported.
+// Returns no entries if there is no index file yet, like in a new repository
+func readGitIndex(indexPath string) ([]gitIndexEntry, error) {
+	data, err := os.ReadFile(indexPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	invalid := errors.New("Invalid git index " + indexPath)
+
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, invalid
+	}
+
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, errors.New("Unsupported git index version " + strconv.FormatUint(uint64(version), 10))
+	}
+
+	count := binary.BigEndian.Uint32(data[8:12])
+
+	// Each entry starts with 40 bytes of file stat data we don't need, the 20-byte hash and 2 bytes of flags
+	const entryHeaderSize = 62
+
+	entries := make([]gitIndexEntry, 0, min(int(count), len(data)/entryHeaderSize))
+	offset := 12
+	previousPath := ""
+	for i := uint32(0); i < count; i++ {
+		if len(data) < offset+entryHeaderSize {
+			return nil, invalid
+		}
+
+		entry := data[offset:]
+		mode := binary.BigEndian.Uint32(entry[24:28])
+		hash := hex.EncodeToString(entry[40:60])
+		flags := binary.BigEndian.Uint16(entry[60:62])
+
+		pathStart := offset + entryHeaderSize
+		if version >= 3 && flags&0x4000 != 0 {
+			pathStart += 2 // Extended flags
+		}
+		if pathStart > len(data) {
+			return nil, invalid
+		}
+
+		var path string
+		if version == 4 {
+			// The path is stored as how many bytes to remove from the end of the previous path, and what to add after that
+			remove, length := readGitIndexVarint(data[pathStart:])
+			if length <= 0 || remove > uint64(len(previousPath)) {
+				return nil, invalid
+			}
+			pathStart += length
+
+			pathLength := bytes.IndexByte(data[pathStart:], 0)
+			if pathLength < 0 {
+				return nil, invalid
+			}
+
+			path = previousPath[:len(previousPath)-int(remove)] + string(data[pathStart:pathStart+pathLength])
+			offset = pathStart + pathLength + 1
+		} else {
+			pathLength := bytes.IndexByte(data[pathStart:], 0)
+			if pathLength < 0 {
+				return nil, invalid
+			}
+
+			path = string(data[pathStart : pathStart+pathLength])
+
+			// Entries are padded with 1 to 8 null bytes to a multiple of 8 bytes
+			entryLength := pathStart + pathLength - offset
+			offset += entryLength + 8 - entryLength%8
+		}
+
+		previousPath = path
+		entries = append(entries, gitIndexEntry{
+			path:  path,
+			mode:  mode,
+			hash:  hash,
+			stage: int(flags>>12) & 3,
+		})
+	}
+
+	return entries, nil
+}
+
+// Returns the number at the start of data in the variable-length encoding of index version 4, and how many bytes it took up.
+// Returns a length of 0 if data doesn't start with a valid number
+func readGitIndexVarint(data []byte) (uint64, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+
+	value := uint64(data[0] & 0x7f)
+	i := 0
+	for data[i]&0x80 != 0 {
+		i++
+		if i >= len(data) || i >= 9 {
+			return 0, 0
+		}
+		value = ((value + 1) << 7) | uint64(data[i]&0x7f)
+	}
+
+	return value, i + 1
+}
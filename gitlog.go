@@ -0,0 +1,294 @@
+package main
+
+//lint:file-ignore ST1005 some user-visible messages are stored in error values and thus occasionally require capitalization
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// The right pane can show the git diff, log or blame of the selected file instead of previewing it
+const (
+	GIT_PREVIEW_OFF = iota
+	GIT_PREVIEW_DIFF
+	GIT_PREVIEW_LOG
+	GIT_PREVIEW_BLAME
+)
+
+// How many commits the git log preview shows, there's rarely room for more
+const gitLogMaxEntries = 200
+
+type GitLogEntry struct {
+	Commit  string
+	Author  string
+	Time    time.Time
+	Subject string
+}
+
+type GitBlameLine struct {
+	Commit string // All zeroes for lines not committed yet
+	Author string
+	Time   time.Time
+	Text   string
+}
+
+// Returns the most recent commits changing the file at path, newest first.
+// Uses the git command when installed, otherwise the git object database is read directly
+func GitLog(repositoryPath, path string) ([]GitLogEntry, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return gitLogFromObjects(repositoryPath, path)
+	}
+
+	output, err := RunGit(repositoryPath, "log", "--max-count="+strconv.Itoa(gitLogMaxEntries), "--follow", "--format=%H%x00%an%x00%at%x00%s", "--", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []GitLogEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+
+		unixTime, _ := strconv.ParseInt(fields[2], 10, 64)
+		entries = append(entries, GitLogEntry{Commit: fields[0], Author: fields[1], Time: time.Unix(unixTime, 0), Subject: fields[3]})
+	}
+
+	return entries, nil
+}
+
+// Walks the first parents from HEAD, listing the commits where the file at path differs from the parent.
+// Unlike git log, renames aren't followed and changes merged from other branches are attributed to the merge commit
+func gitLogFromObjects(repositoryPath, path string) ([]GitLogEntry, error) {
+	relativePath, err := filepath.Rel(repositoryPath, path)
+	if err != nil {
+		return nil, err
+	}
+	relativePath = filepath.ToSlash(relativePath)
+
+	gitDir, err := GitDir(repositoryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := ResolveGitRef(gitDir, "HEAD")
+	if err != nil {
+		// No commits yet
+		return nil, nil
+	}
+
+	objects := NewGitObjectReader(GitCommonDir(gitDir))
+	defer objects.Close()
+
+	commit, err := objects.ReadCommit(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	fileHash, err := objects.TreePathHash(commit.tree, relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []GitLogEntry
+	for i := 0; i < gitAheadBehindMaxCommits && len(entries) < gitLogMaxEntries; i++ {
+		var parent gitCommit
+		var parentFileHash string
+		if len(commit.parents) > 0 {
+			parent, err = objects.ReadCommit(commit.parents[0])
+			if err != nil {
+				return entries, err
+			}
+
+			parentFileHash, err = objects.TreePathHash(parent.tree, relativePath)
+			if err != nil {
+				return entries, err
+			}
+		}
+
+		if fileHash != parentFileHash {
+			entries = append(entries, GitLogEntry{Commit: hash, Author: commit.author, Time: time.Unix(commit.authorTime, 0), Subject: commit.subject})
+		}
+
+		if len(commit.parents) == 0 {
+			break
+		}
+
+		hash, commit, fileHash = commit.parents[0], parent, parentFileHash
+	}
+
+	return entries, nil
+}
+
+// Returns who last changed each line of the file at path, requires the git command
+func GitBlame(repositoryPath, path string) ([]GitBlameLine, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New("Git blame requires git to be installed")
+	}
+
+	output, err := RunGit(repositoryPath, "blame", "--porcelain", "--", path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseGitBlamePorcelain(output), nil
+}
+
+// Parses the output of "git blame --porcelain".
+// Each line of the file is preceded by a "<commit> <original line> <final line>" header,
+// and the first time a commit appears, by headers like "author" and "author-time"
+func parseGitBlamePorcelain(output []byte) []GitBlameLine {
+	commits := make(map[string]*GitBlameLine)
+	var current *GitBlameLine
+
+	var lines []GitBlameLine
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if text, isContent := strings.CutPrefix(line, "\t"); isContent {
+			if current != nil {
+				blameLine := *current
+				blameLine.Text = text
+				lines = append(lines, blameLine)
+			}
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		if _, err := hex.DecodeString(key); err == nil && len(key) == 40 {
+			var ok bool
+			current, ok = commits[key]
+			if !ok {
+				current = &GitBlameLine{Commit: key}
+				commits[key] = current
+			}
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		switch key {
+		case "author":
+			current.Author = value
+		case "author-time":
+			unixTime, _ := strconv.ParseInt(value, 10, 64)
+			current.Time = time.Unix(unixTime, 0)
+		}
+	}
+
+	return lines
+}
+
+type gitHistoryPreview struct {
+	key     string
+	path    string // The file and the kind of preview, the lines are kept while running again for the same one
+	lines   []string
+	err     error
+	done    bool // False until the first result for path
+	running bool
+}
+
+// Returns the git log or blame of the file at path, depending on fen.gitPreview, as lines with tview color tags.
+// Returns no lines if the file is not in a local Git repository, or has no commits.
+// Reading the history can take seconds, so it's done in a separate thread when the file or the checked out commit changes, redrawing when it's done.
+// Until then, the previous lines of the same file are returned, and loading is true if there are none.
+// Only call this from the UI thread
+func (fen *Fen) GitHistoryPreview(path string) (lines []string, loading bool, err error) {
+	repositoryPath, err := fen.gitStatusHandler.TryFindParentGitRepository(path)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// The git status handler might not be running, so we look at the checked out commit ourselves
+	var commit string
+	if gitDir, err := GitDir(repositoryPath); err == nil {
+		commit, _ = ResolveGitRef(gitDir, "HEAD")
+	}
+
+	previewPath := strconv.Itoa(fen.gitPreview) + "\x00" + path
+	key := previewPath + "\x00" + stat.ModTime().String() + "\x00" + commit
+	if fen.gitHistoryPreview.key == key && !fen.gitHistoryPreview.running {
+		return fen.gitHistoryPreview.lines, false, fen.gitHistoryPreview.err
+	}
+
+	if fen.gitHistoryPreview.path != previewPath {
+		fen.gitHistoryPreview = gitHistoryPreview{path: previewPath, running: fen.gitHistoryPreview.running}
+	}
+
+	loading = !fen.gitHistoryPreview.done
+
+	// Waits for the one already running, a new one is started when it's done if the key changed
+	if fen.gitHistoryPreview.key == key || fen.gitHistoryPreview.running {
+		fen.gitHistoryPreview.key = key
+		return fen.gitHistoryPreview.lines, loading, fen.gitHistoryPreview.err
+	}
+	fen.gitHistoryPreview.key = key
+	fen.gitHistoryPreview.running = true
+
+	gitPreview := fen.gitPreview
+	go func() {
+		lines, err := gitHistoryPreviewLines(gitPreview, repositoryPath, path)
+		fen.app.QueueUpdateDraw(func() {
+			fen.gitHistoryPreview.running = false
+			if fen.gitHistoryPreview.key == key {
+				fen.gitHistoryPreview.lines = lines
+				fen.gitHistoryPreview.err = err
+				fen.gitHistoryPreview.done = true
+			} else {
+				fen.gitHistoryPreview.key = "" // Changed while running, so the next draw starts over
+			}
+		})
+	}()
+
+	return fen.gitHistoryPreview.lines, loading, fen.gitHistoryPreview.err
+}
+
+// Returns the lines of the git log or blame preview of the file at path, depending on gitPreview
+func gitHistoryPreviewLines(gitPreview int, repositoryPath, path string) ([]string, error) {
+	var lines []string
+	if gitPreview == GIT_PREVIEW_LOG {
+		entries, err := GitLog(repositoryPath, path)
+		for _, e := range entries {
+			lines = append(lines, "[yellow]"+e.Commit[:min(7, len(e.Commit))]+"[-] "+e.Time.Format("2006-01-02")+" [::d]"+tview.Escape(e.Author)+"[::-] "+tview.Escape(e.Subject))
+		}
+		return lines, err
+	}
+
+	blameLines, err := GitBlame(repositoryPath, path)
+	const authorWidth = 13
+	for i, e := range blameLines {
+		// Only show who changed a line when it differs from the line above
+		annotation := strings.Repeat(" ", 7+1+10+1+authorWidth)
+		if i == 0 || e.Commit != blameLines[i-1].Commit {
+			author := []rune(e.Author)
+			author = author[:min(len(author), authorWidth)]
+			annotation = "[yellow]" + e.Commit[:7] + "[-] " + e.Time.Format("2006-01-02") + " [::d]" + tview.Escape(string(author)) + strings.Repeat(" ", authorWidth-len(author)) + "[::-]"
+			if strings.Trim(e.Commit, "0") == "" {
+				annotation = "[::d]" + strings.Repeat(" ", 7+1+10+1) + "Not committed[::-]"
+			}
+		}
+
+		lines = append(lines, annotation+" "+tview.Escape(strings.ReplaceAll(e.Text, "\t", "    ")))
+	}
+	return lines, err
+}
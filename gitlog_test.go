@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGitLog(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repositoryPath := t.TempDir()
+	testGit(t, repositoryPath, "init", "--quiet")
+	testGitCommit(t, repositoryPath, "other")
+
+	err := os.Mkdir(filepath.Join(repositoryPath, "folder"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(repositoryPath, "folder", "file")
+	for i := 0; i < 3; i++ {
+		err := os.WriteFile(file, []byte("line "+strconv.Itoa(i)+"\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testGit(t, repositoryPath, "add", file)
+		testGit(t, repositoryPath, "commit", "--quiet", "-m", "Change "+strconv.Itoa(i))
+		testGitCommit(t, repositoryPath, "unrelated"+strconv.Itoa(i))
+	}
+
+	expected := strings.Fields(testGit(t, repositoryPath, "log", "--format=%H", "--", file))
+
+	check := func(when string, entries []GitLogEntry, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != len(expected) {
+			t.Fatalf(when + ": Expected " + strconv.Itoa(len(expected)) + " commits, but got " + strconv.Itoa(len(entries)))
+		}
+		for i, e := range entries {
+			if e.Commit != expected[i] || e.Author != "fen" || e.Subject != "Change "+strconv.Itoa(len(entries)-1-i) {
+				t.Fatalf(when + ": Expected commit " + expected[i] + ", but got " + e.Commit + " \"" + e.Subject + "\" by \"" + e.Author + "\"")
+			}
+		}
+	}
+
+	entries, err := GitLog(repositoryPath, file)
+	check("Git command", entries, err)
+
+	entries, err = gitLogFromObjects(repositoryPath, file)
+	check("Loose objects", entries, err)
+
+	testGit(t, repositoryPath, "gc", "--quiet")
+	entries, err = gitLogFromObjects(repositoryPath, file)
+	check("Packed objects", entries, err)
+
+	err = os.WriteFile(file, []byte("line 2\nnew line\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blameLines, err := GitBlame(repositoryPath, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blameLines) != 2 {
+		t.Fatalf("Expected 2 blamed lines, but got " + strconv.Itoa(len(blameLines)))
+	}
+	if blameLines[0].Commit != expected[0] || blameLines[0].Author != "fen" || blameLines[0].Text != "line 2" {
+		t.Fatalf("Expected the first line to be from the last change, but got " + blameLines[0].Commit + " by \"" + blameLines[0].Author + "\": \"" + blameLines[0].Text + "\"")
+	}
+	if strings.Trim(blameLines[1].Commit, "0") != "" || blameLines[1].Text != "new line" {
+		t.Fatalf("Expected the second line not to be committed, but got " + blameLines[1].Commit + ": \"" + blameLines[1].Text + "\"")
+	}
+}
@@ -0,0 +1,695 @@
+package main
+
+//lint:file-ignore ST1005 some user-visible messages are stored in error values and thus occasionally require capitalization
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"container/heap"
+	"container/list"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Reads objects from the .git/objects folder, both loose and in packfiles, without the git command.
+// Only SHA-1 repositories are supported
+type GitObjectReader struct {
+	gitDir     string
+	packs      []*gitPack // Loaded on first use
+	loaded     bool
+	deltaBases gitDeltaBaseCache
+}
+
+// Recently inflated objects in packfiles which deltas were based on.
+// Objects in a delta chain are often the base of other objects read soon after, like the trees in the same folder,
+// so without this, every object would inflate its whole delta chain again
+type gitDeltaBaseCache struct {
+	entries map[gitDeltaBaseKey]*list.Element
+	order   list.List // Of *gitDeltaBase, the most recently used first
+	bytes   int
+}
+
+type gitDeltaBaseKey struct {
+	file   *os.File
+	offset uint64
+}
+
+type gitDeltaBase struct {
+	key        gitDeltaBaseKey
+	objectType int
+	data       []byte
+}
+
+// How much memory the delta bases of a GitObjectReader can use
+const gitDeltaBaseCacheBytes = 16 * 1024 * 1024
+
+// The data is shared, so it must not be modified
+func (c *gitDeltaBaseCache) get(key gitDeltaBaseKey) (int, []byte, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return 0, nil, false
+	}
+
+	c.order.MoveToFront(element)
+	base := element.Value.(*gitDeltaBase)
+	return base.objectType, base.data, true
+}
+
+func (c *gitDeltaBaseCache) add(key gitDeltaBaseKey, objectType int, data []byte) {
+	if len(data) > gitDeltaBaseCacheBytes/4 {
+		return
+	}
+
+	if c.entries == nil {
+		c.entries = make(map[gitDeltaBaseKey]*list.Element)
+	}
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&gitDeltaBase{key: key, objectType: objectType, data: data})
+	c.bytes += len(data)
+
+	for c.bytes > gitDeltaBaseCacheBytes {
+		oldest := c.order.Remove(c.order.Back()).(*gitDeltaBase)
+		delete(c.entries, oldest.key)
+		c.bytes -= len(oldest.data)
+	}
+}
+
+type gitPack struct {
+	packPath string
+	file     *os.File // Opened on first use
+	hashes   []byte   // Sorted 20-byte hashes from the .idx file
+	offsets  []uint64
+}
+
+const (
+	gitObjectCommit   = 1
+	gitObjectTree     = 2
+	gitObjectBlob     = 3
+	gitObjectTag      = 4
+	gitObjectOfsDelta = 6
+	gitObjectRefDelta = 7
+)
+
+// The file type bits of the modes in tree objects and the index
+const (
+	gitModeTypeMask = 0o170000
+	gitModeTree     = 0o040000
+	gitModeGitlink  = 0o160000 // A submodule, the hash is the commit it's at
+)
+
+func NewGitObjectReader(gitDir string) *GitObjectReader {
+	return &GitObjectReader{gitDir: gitDir}
+}
+
+// Closes the packfiles opened while reading objects
+func (r *GitObjectReader) Close() {
+	for _, pack := range r.packs {
+		if pack.file != nil {
+			pack.file.Close()
+		}
+	}
+}
+
+// Returns the type and data of the object with the hex hash
+func (r *GitObjectReader) ReadObject(hash string) (int, []byte, error) {
+	if len(hash) != 40 {
+		return 0, nil, errors.New("Unsupported object hash: " + hash)
+	}
+
+	objectType, data, err := r.readLooseObject(hash)
+	if err == nil {
+		return objectType, data, nil
+	}
+
+	rawHash, err := hex.DecodeString(hash)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return r.readPackedObject(rawHash)
+}
+
+func (r *GitObjectReader) readLooseObject(hash string) (int, []byte, error) {
+	file, err := os.Open(filepath.Join(r.gitDir, "objects", hash[:2], hash[2:]))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.Close()
+
+	reader, err := zlib.NewReader(file)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// The header looks like "commit 123\x00"
+	header, content, found := bytes.Cut(data, []byte{0})
+	if !found {
+		return 0, nil, errors.New("Invalid loose object " + hash)
+	}
+
+	typeName, _, _ := strings.Cut(string(header), " ")
+	switch typeName {
+	case "commit":
+		return gitObjectCommit, content, nil
+	case "tree":
+		return gitObjectTree, content, nil
+	case "blob":
+		return gitObjectBlob, content, nil
+	case "tag":
+		return gitObjectTag, content, nil
+	}
+
+	return 0, nil, errors.New("Unknown object type \"" + typeName + "\"")
+}
+
+func (r *GitObjectReader) loadPacks() {
+	if r.loaded {
+		return
+	}
+	r.loaded = true
+
+	indexPaths, _ := filepath.Glob(filepath.Join(r.gitDir, "objects", "pack", "*.idx"))
+	for _, indexPath := range indexPaths {
+		pack, err := loadGitPackIndex(indexPath)
+		if err == nil {
+			r.packs = append(r.packs, pack)
+		}
+	}
+}
+
+// Parses a version 2 pack index file
+func loadGitPackIndex(indexPath string) (*gitPack, error) {
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 8+256*4 || !bytes.Equal(data[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(data[4:8]) != 2 {
+		return nil, errors.New("Unsupported pack index " + indexPath)
+	}
+
+	count := int(binary.BigEndian.Uint32(data[8+255*4:]))
+	hashesStart := 8 + 256*4
+	offsetsStart := hashesStart + count*20 + count*4 // Skipping the CRC32 checksums
+	largeOffsetsStart := offsetsStart + count*4
+	if len(data) < largeOffsetsStart {
+		return nil, errors.New("Invalid pack index " + indexPath)
+	}
+
+	pack := &gitPack{
+		packPath: strings.TrimSuffix(indexPath, ".idx") + ".pack",
+		hashes:   data[hashesStart : hashesStart+count*20],
+		offsets:  make([]uint64, count),
+	}
+
+	for i := 0; i < count; i++ {
+		offset := binary.BigEndian.Uint32(data[offsetsStart+i*4:])
+		if offset&0x80000000 == 0 {
+			pack.offsets[i] = uint64(offset)
+			continue
+		}
+
+		// Offsets over 2GB are stored in a separate table
+		largeOffsetIndex := largeOffsetsStart + int(offset&0x7fffffff)*8
+		if len(data) < largeOffsetIndex+8 {
+			return nil, errors.New("Invalid pack index " + indexPath)
+		}
+		pack.offsets[i] = binary.BigEndian.Uint64(data[largeOffsetIndex:])
+	}
+
+	return pack, nil
+}
+
+func (pack *gitPack) find(rawHash []byte) (uint64, bool) {
+	count := len(pack.offsets)
+	i := sort.Search(count, func(i int) bool {
+		return bytes.Compare(pack.hashes[i*20:i*20+20], rawHash) >= 0
+	})
+
+	if i < count && bytes.Equal(pack.hashes[i*20:i*20+20], rawHash) {
+		return pack.offsets[i], true
+	}
+	return 0, false
+}
+
+func (r *GitObjectReader) readPackedObject(rawHash []byte) (int, []byte, error) {
+	r.loadPacks()
+
+	for _, pack := range r.packs {
+		offset, ok := pack.find(rawHash)
+		if !ok {
+			continue
+		}
+
+		if pack.file == nil {
+			file, err := os.Open(pack.packPath)
+			if err != nil {
+				return 0, nil, err
+			}
+			pack.file = file
+		}
+
+		return r.readPackObjectAt(pack.file, offset, 0)
+	}
+
+	return 0, nil, errors.New("Object not found " + hex.EncodeToString(rawHash))
+}
+
+// Deltas can be based on other deltas, this limits how deep we go
+const gitMaxDeltaDepth = 100
+
+func (r *GitObjectReader) readPackObjectAt(file *os.File, offset uint64, depth int) (int, []byte, error) {
+	if depth > gitMaxDeltaDepth {
+		return 0, nil, errors.New("Delta chain too long")
+	}
+
+	// Buffered, since the headers are read a byte at a time
+	reader := bufio.NewReader(io.NewSectionReader(file, int64(offset), 1<<62))
+
+	// The type and size header, the size is a variable-length integer
+	b, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	objectType := int(b>>4) & 0b111
+	for b&0x80 != 0 {
+		b, err = reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	switch objectType {
+	case gitObjectCommit, gitObjectTree, gitObjectBlob, gitObjectTag:
+		data, err := inflate(reader)
+		return objectType, data, err
+	case gitObjectOfsDelta:
+		// The base is at a negative offset, encoded differently from the size above
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		baseOffset := uint64(b & 0x7f)
+		for b&0x80 != 0 {
+			b, err = reader.ReadByte()
+			if err != nil {
+				return 0, nil, err
+			}
+			baseOffset = ((baseOffset + 1) << 7) | uint64(b&0x7f)
+		}
+
+		if baseOffset > offset {
+			return 0, nil, errors.New("Invalid delta base offset")
+		}
+
+		delta, err := inflate(reader)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		baseKey := gitDeltaBaseKey{file: file, offset: offset - baseOffset}
+		baseType, base, ok := r.deltaBases.get(baseKey)
+		if !ok {
+			baseType, base, err = r.readPackObjectAt(file, baseKey.offset, depth+1)
+			if err != nil {
+				return 0, nil, err
+			}
+			r.deltaBases.add(baseKey, baseType, base)
+		}
+
+		data, err := applyGitDelta(base, delta)
+		return baseType, data, err
+	case gitObjectRefDelta:
+		baseHash := make([]byte, 20)
+		_, err := io.ReadFull(reader, baseHash)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		delta, err := inflate(reader)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		baseType, base, err := r.ReadObject(hex.EncodeToString(baseHash))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		data, err := applyGitDelta(base, delta)
+		return baseType, data, err
+	}
+
+	return 0, nil, errors.New("Unknown packed object type " + strconv.Itoa(objectType))
+}
+
+func inflate(reader io.Reader) ([]byte, error) {
+	zlibReader, err := zlib.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer zlibReader.Close()
+
+	return io.ReadAll(zlibReader)
+}
+
+// Rebuilds an object from its base and a delta from a packfile
+func applyGitDelta(base, delta []byte) ([]byte, error) {
+	invalid := errors.New("Invalid delta")
+
+	readSize := func() (uint64, bool) {
+		var size uint64
+		for shift := 0; len(delta) > 0; shift += 7 {
+			b := delta[0]
+			delta = delta[1:]
+			size |= uint64(b&0x7f) << shift
+			if b&0x80 == 0 {
+				return size, true
+			}
+		}
+		return 0, false
+	}
+
+	baseSize, ok := readSize()
+	if !ok || baseSize != uint64(len(base)) {
+		return nil, invalid
+	}
+
+	resultSize, ok := readSize()
+	if !ok {
+		return nil, invalid
+	}
+
+	result := make([]byte, 0, resultSize)
+	for len(delta) > 0 {
+		instruction := delta[0]
+		delta = delta[1:]
+
+		if instruction&0x80 == 0 {
+			// Insert the next bytes
+			length := int(instruction)
+			if length == 0 || length > len(delta) {
+				return nil, invalid
+			}
+			result = append(result, delta[:length]...)
+			delta = delta[length:]
+			continue
+		}
+
+		// Copy from the base, the bits say which offset and size bytes follow
+		var copyOffset, copyLength uint64
+		for i := 0; i < 7; i++ {
+			if instruction&(1<<i) == 0 {
+				continue
+			}
+			if len(delta) == 0 {
+				return nil, invalid
+			}
+
+			if i < 4 {
+				copyOffset |= uint64(delta[0]) << (8 * i)
+			} else {
+				copyLength |= uint64(delta[0]) << (8 * (i - 4))
+			}
+			delta = delta[1:]
+		}
+
+		if copyLength == 0 {
+			copyLength = 0x10000
+		}
+		if copyOffset+copyLength > uint64(len(base)) {
+			return nil, invalid
+		}
+		result = append(result, base[copyOffset:copyOffset+copyLength]...)
+	}
+
+	if uint64(len(result)) != resultSize {
+		return nil, invalid
+	}
+	return result, nil
+}
+
+type gitCommit struct {
+	tree       string
+	parents    []string
+	commitTime int64 // Unix time from the committer line
+	author     string
+	authorTime int64
+	subject    string // The first line of the commit message
+}
+
+// Returns the name and Unix time of a "Name <email> 1700000000 +0100" author or committer line
+func parseGitSignature(value string) (string, int64) {
+	name, rest, _ := strings.Cut(value, " <")
+	fields := strings.Fields(rest)
+	if len(fields) < 2 {
+		return name, 0
+	}
+
+	unixTime, _ := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+	return name, unixTime
+}
+
+func (r *GitObjectReader) ReadCommit(hash string) (gitCommit, error) {
+	var commit gitCommit
+
+	objectType, data, err := r.ReadObject(hash)
+	if err != nil {
+		return commit, err
+	}
+
+	if objectType != gitObjectCommit {
+		return commit, errors.New("Not a commit " + hash)
+	}
+
+	// The headers end at the first empty line, followed by the commit message
+	headers, message, _ := strings.Cut(string(data), "\n\n")
+	for _, line := range strings.Split(headers, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			commit.tree = value
+		case "parent":
+			commit.parents = append(commit.parents, value)
+		case "author":
+			commit.author, commit.authorTime = parseGitSignature(value)
+		case "committer":
+			_, commit.commitTime = parseGitSignature(value)
+		}
+	}
+
+	commit.subject, _, _ = strings.Cut(message, "\n")
+	return commit, nil
+}
+
+// Returns the hex hash of the object at the slash-separated relativePath in the tree with the hex hash treeHash.
+// Returns an empty string if there is nothing at relativePath
+func (r *GitObjectReader) TreePathHash(treeHash, relativePath string) (string, error) {
+	hash := treeHash
+	for _, name := range strings.Split(relativePath, "/") {
+		objectType, data, err := r.ReadObject(hash)
+		if err != nil {
+			return "", err
+		}
+
+		if objectType != gitObjectTree {
+			return "", nil
+		}
+
+		hash = ""
+
+		// Each entry is "<mode> <name>\0<20-byte hash>"
+		for len(data) > 0 {
+			nameStart := bytes.IndexByte(data, ' ') + 1
+			nameEnd := bytes.IndexByte(data, 0)
+			if nameStart <= 0 || nameEnd < nameStart || len(data) < nameEnd+21 {
+				return "", errors.New("Invalid tree object " + treeHash)
+			}
+
+			if string(data[nameStart:nameEnd]) == name {
+				hash = hex.EncodeToString(data[nameEnd+1 : nameEnd+21])
+				break
+			}
+			data = data[nameEnd+21:]
+		}
+
+		if hash == "" {
+			return "", nil
+		}
+	}
+
+	return hash, nil
+}
+
+type gitTreeFile struct {
+	mode uint32
+	hash string
+}
+
+// Adds every file in the tree with the hex hash treeHash to files, keyed by their slash-separated path after prefix.
+// Submodules are added like files with the gitlink mode, since their files are in another repository
+func (r *GitObjectReader) TreeFiles(ctx context.Context, treeHash, prefix string, files map[string]gitTreeFile) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	objectType, data, err := r.ReadObject(treeHash)
+	if err != nil {
+		return err
+	}
+
+	if objectType != gitObjectTree {
+		return errors.New("Not a tree " + treeHash)
+	}
+
+	// Each entry is "<mode> <name>\0<20-byte hash>"
+	for len(data) > 0 {
+		modeEnd := bytes.IndexByte(data, ' ')
+		nameEnd := bytes.IndexByte(data, 0)
+		if modeEnd <= 0 || nameEnd <= modeEnd || len(data) < nameEnd+21 {
+			return errors.New("Invalid tree object " + treeHash)
+		}
+
+		mode, err := strconv.ParseUint(string(data[:modeEnd]), 8, 32)
+		if err != nil {
+			return errors.New("Invalid tree object " + treeHash)
+		}
+
+		path := prefix + string(data[modeEnd+1:nameEnd])
+		hash := hex.EncodeToString(data[nameEnd+1 : nameEnd+21])
+		data = data[nameEnd+21:]
+
+		if mode&gitModeTypeMask == gitModeTree {
+			err = r.TreeFiles(ctx, hash, path+"/", files)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		files[path] = gitTreeFile{mode: uint32(mode), hash: hash}
+	}
+
+	return nil
+}
+
+type gitCommitQueue struct {
+	hashes  []string
+	commits map[string]gitCommit
+}
+
+func (q *gitCommitQueue) Len() int { return len(q.hashes) }
+func (q *gitCommitQueue) Less(i, j int) bool {
+	return q.commits[q.hashes[i]].commitTime > q.commits[q.hashes[j]].commitTime
+}
+func (q *gitCommitQueue) Swap(i, j int) { q.hashes[i], q.hashes[j] = q.hashes[j], q.hashes[i] }
+func (q *gitCommitQueue) Push(x any)    { q.hashes = append(q.hashes, x.(string)) }
+func (q *gitCommitQueue) Pop() any {
+	last := q.hashes[len(q.hashes)-1]
+	q.hashes = q.hashes[:len(q.hashes)-1]
+	return last
+}
+
+// Reading more commits than this to count ahead/behind takes too long, so we give up
+const gitAheadBehindMaxCommits = 10000
+
+// Counts the commits only reachable from local, and those only reachable from upstream, like "git rev-list --left-right --count local...upstream".
+// Walks both histories newest first, until every commit left to visit is reachable from both
+func (r *GitObjectReader) AheadBehind(local, upstream string) (ahead, behind int, err error) {
+	const (
+		fromLocal    = 1
+		fromUpstream = 2
+		fromBoth     = fromLocal | fromUpstream
+	)
+
+	flags := make(map[string]int)
+	queue := &gitCommitQueue{commits: make(map[string]gitCommit)}
+
+	// Counted as we go, instead of looking through the queue after every commit
+	queued := make(map[string]int) // How many times each commit is in the queue
+	interesting := 0               // Commits in the queue not reachable from both
+
+	push := func(hash string, flag int) error {
+		if flags[hash]|flag == flags[hash] {
+			return nil
+		}
+
+		_, alreadyRead := queue.commits[hash]
+		if !alreadyRead {
+			if len(queue.commits) >= gitAheadBehindMaxCommits {
+				return errors.New("Too many commits to count")
+			}
+
+			commit, err := r.ReadCommit(hash)
+			if err != nil {
+				return err
+			}
+			queue.commits[hash] = commit
+		}
+
+		wasInteresting := queued[hash] > 0 && flags[hash] != fromBoth
+		flags[hash] |= flag
+		queued[hash]++
+		heap.Push(queue, hash)
+
+		if isInteresting := flags[hash] != fromBoth; isInteresting != wasInteresting {
+			if isInteresting {
+				interesting++
+			} else {
+				interesting--
+			}
+		}
+		return nil
+	}
+
+	if err := push(local, fromLocal); err != nil {
+		return 0, 0, err
+	}
+	if err := push(upstream, fromUpstream); err != nil {
+		return 0, 0, err
+	}
+
+	// Stops when every commit left to visit is reachable from both
+	for queue.Len() > 0 && interesting > 0 {
+		hash := heap.Pop(queue).(string)
+		queued[hash]--
+		if queued[hash] == 0 && flags[hash] != fromBoth {
+			interesting--
+		}
+
+		for _, parent := range queue.commits[hash].parents {
+			if err := push(parent, flags[hash]); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+
+	for _, flag := range flags {
+		switch flag {
+		case fromLocal:
+			ahead++
+		case fromUpstream:
+			behind++
+		}
+	}
+
+	return ahead, behind, nil
+}
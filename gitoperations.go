@@ -0,0 +1,199 @@
+package main
+
+//lint:file-ignore ST1005 some user-visible messages are stored in error values and thus occasionally require capitalization
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Runs the git command in the repository at repositoryPath, returning its output.
+// The error contains the first line git printed to stderr, so it can be shown in the bottom bar
+func RunGit(repositoryPath string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", repositoryPath}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		message, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+		if message == "" {
+			message = err.Error()
+		}
+		return output, errors.New(message)
+	}
+
+	return output, nil
+}
+
+// Returns the selected files, or the file under the cursor if none are selected
+func (fen *Fen) selectedOrSel() []string {
+	if len(fen.selected) <= 0 {
+		return []string{fen.sel}
+	}
+
+	paths := make([]string, 0, len(fen.selected))
+	for path := range fen.selected {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// Groups paths by the local Git repository containing them, paths outside of any repository are left out
+func (fen *Fen) pathsByGitRepository(paths []string) map[string][]string {
+	repositories := make(map[string][]string)
+	for _, path := range paths {
+		repositoryPath, err := fen.gitStatusHandler.TryFindParentGitRepository(path)
+		if err != nil {
+			continue
+		}
+		repositories[repositoryPath] = append(repositories[repositoryPath], path)
+	}
+
+	return repositories
+}
+
+// Runs a git command like "git add" on paths, adding them as pathspecs after "--".
+// Returns a text for the bottom bar, like "Staged 3 files", or the error of the first repository it failed in
+func (fen *Fen) runGitOnPaths(paths []string, doneText string, args ...string) (string, error) {
+	if fen.config.NoWrite {
+		return "", errors.New("Can't run git commands in no-write mode")
+	}
+
+	repositories := fen.pathsByGitRepository(paths)
+	if len(repositories) == 0 {
+		return "", errors.New("Not in a local Git repository")
+	}
+
+	sortedRepositories := make([]string, 0, len(repositories))
+	for repositoryPath := range repositories {
+		sortedRepositories = append(sortedRepositories, repositoryPath)
+	}
+	slices.Sort(sortedRepositories)
+
+	// Keeps going after a repository fails, so the result doesn't depend on which repository came first
+	var doneFiles []string
+	var failedRepositories []string
+	var firstErr error
+	for _, repositoryPath := range sortedRepositories {
+		_, err := RunGit(repositoryPath, append(append(slices.Clone(args), "--"), repositories[repositoryPath]...)...)
+		if err != nil {
+			failedRepositories = append(failedRepositories, repositoryPath)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		doneFiles = append(doneFiles, repositories[repositoryPath]...)
+	}
+
+	// The git index file watcher might only be watching one of the repositories
+	fen.TriggerGitStatus()
+
+	if len(failedRepositories) > 0 {
+		text := firstErr.Error()
+		if len(repositories) > 1 {
+			text = "Failed in " + filepath.Base(failedRepositories[0]) + ": " + text
+			if len(failedRepositories) > 1 {
+				text += " (and " + strconv.Itoa(len(failedRepositories)-1) + " other repositories)"
+			}
+		}
+		return "", errors.New(text)
+	}
+
+	if len(doneFiles) == 1 {
+		return doneText + " " + filepath.Base(doneFiles[0]), nil
+	}
+	return doneText + " " + strconv.Itoa(len(doneFiles)) + " files", nil
+}
+
+// Like "git add", stages the changes of paths
+func (fen *Fen) GitStage(paths []string) (string, error) {
+	return fen.runGitOnPaths(paths, "Staged", "add")
+}
+
+// Like "git restore --staged", removes the staged changes of paths from the index, keeping them in the files.
+// Uses "git reset" since it also works in a repository without any commits
+func (fen *Fen) GitUnstage(paths []string) (string, error) {
+	return fen.runGitOnPaths(paths, "Unstaged", "reset", "--quiet")
+}
+
+// Like "git restore", discards the unstaged changes of paths.
+// This can't be undone, so ask the user first
+func (fen *Fen) GitRestore(paths []string) (string, error) {
+	return fen.runGitOnPaths(paths, "Restored", "restore")
+}
+
+// Returns the colored diff of the file at path for the git diff preview.
+// Changes not yet staged are shown against the index, otherwise the staged changes are shown against HEAD.
+// Returns an empty diff if the file is unchanged or not in a local Git repository
+func (fen *Fen) GitDiff(path string) ([]byte, error) {
+	repositoryPath, err := fen.gitStatusHandler.TryFindParentGitRepository(path)
+	if err != nil {
+		return nil, nil
+	}
+
+	diff, err := RunGit(repositoryPath, "diff", "--color=always", "--", path)
+	if err != nil || len(diff) > 0 {
+		return diff, err
+	}
+
+	return RunGit(repositoryPath, "diff", "--cached", "--color=always", "--", path)
+}
+
+type gitDiffPreview struct {
+	key     string
+	path    string
+	diff    []byte
+	err     error
+	running bool
+}
+
+// Returns the diff of the file at path for the git diff preview, see GitDiff().
+// The diff is run in a separate thread when the file changes or a git status finishes, redrawing when it's done.
+// Until then, the previous diff of the same file is returned, or nothing if there is none.
+// Only call this from the UI thread
+func (fen *Fen) GitDiffPreview(path string) ([]byte, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := path + "\x00" + stat.ModTime().String() + "\x00" + strconv.FormatUint(fen.gitStatusHandler.StatusGeneration(), 10)
+	if fen.gitDiffPreview.key == key {
+		return fen.gitDiffPreview.diff, fen.gitDiffPreview.err
+	}
+
+	if fen.gitDiffPreview.path != path {
+		fen.gitDiffPreview = gitDiffPreview{path: path, running: fen.gitDiffPreview.running}
+	}
+	fen.gitDiffPreview.key = key
+
+	// Waits for the diff already running, a new one is started when it's done if the key changed
+	if fen.gitDiffPreview.running {
+		return fen.gitDiffPreview.diff, fen.gitDiffPreview.err
+	}
+	fen.gitDiffPreview.running = true
+
+	go func() {
+		diff, err := fen.GitDiff(path)
+		fen.app.QueueUpdateDraw(func() {
+			fen.gitDiffPreview.running = false
+			if fen.gitDiffPreview.key == key {
+				fen.gitDiffPreview.diff = diff
+				fen.gitDiffPreview.err = err
+			} else {
+				fen.gitDiffPreview.key = "" // Changed while running, so the next draw starts over
+			}
+		})
+	}()
+
+	return fen.gitDiffPreview.diff, fen.gitDiffPreview.err
+}
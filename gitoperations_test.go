@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitStageAndUnstage(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repositoryPath := t.TempDir()
+	_, err := RunGit(repositoryPath, "init", "--quiet")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(repositoryPath, "file.txt")
+	err = os.WriteFile(file, []byte("hello\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fen := Fen{}
+	status := func() string {
+		output, err := RunGit(repositoryPath, "status", "--porcelain=v1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(output))
+	}
+
+	text, err := fen.GitStage([]string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Staged file.txt" {
+		t.Fatalf("Expected \"Staged file.txt\", but got \"" + text + "\"")
+	}
+	if status() != "A  file.txt" {
+		t.Fatalf("Expected the file to be staged, but got \"" + status() + "\"")
+	}
+
+	// There are no commits yet
+	_, err = fen.GitUnstage([]string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status() != "?? file.txt" {
+		t.Fatalf("Expected the file to be untracked, but got \"" + status() + "\"")
+	}
+
+	_, err = fen.GitStage([]string{filepath.Join(t.TempDir(), "outside.txt")})
+	if err == nil {
+		t.Fatalf("Expected an error staging a file outside of a Git repository")
+	}
+}
@@ -0,0 +1,427 @@
+package main
+
+//lint:file-ignore ST1005 some user-visible messages are stored in error values and thus occasionally require capitalization
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// Returns true if path looks like a bare repository, or a git folder used directly like with GIT_DIR
+func IsBareGitRepository(path string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		_, err := os.Lstat(filepath.Join(path, name))
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Reads a ".git" file, which worktrees and submodules have instead of a ".git" folder.
+// It contains a line like "gitdir: ../.git/modules/name", relative to the folder containing it
+func readGitDirFile(dotGitPath string) (string, error) {
+	data, err := os.ReadFile(dotGitPath)
+	if err != nil {
+		return "", err
+	}
+
+	gitDir, found := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !found {
+		return "", errors.New("Not a gitdir file: " + dotGitPath)
+	}
+
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(dotGitPath), gitDir)
+	}
+	return filepath.Clean(gitDir), nil
+}
+
+// Returns the git folder of the repository at repositoryPath, containing its HEAD and index.
+// This is the ".git" folder, the folder a ".git" file points to for worktrees and submodules, or repositoryPath itself for bare repositories
+func GitDir(repositoryPath string) (string, error) {
+	dotGitPath := filepath.Join(repositoryPath, ".git")
+	stat, err := os.Lstat(dotGitPath)
+	if err == nil {
+		if stat.IsDir() {
+			return dotGitPath, nil
+		}
+		return readGitDirFile(dotGitPath)
+	}
+
+	if IsBareGitRepository(repositoryPath) {
+		return repositoryPath, nil
+	}
+
+	return "", errors.New("Not a local Git repository: " + repositoryPath)
+}
+
+// Returns the folder with the refs, objects and config, which the worktrees of a repository share.
+// Other than for worktrees, this is gitDir
+func GitCommonDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+
+	commonDir := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(gitDir, commonDir)
+	}
+	return filepath.Clean(commonDir)
+}
+
+// Returns the folder containing ref, since each worktree has its own HEAD, but shares the branches with the others
+func gitRefDir(gitDir, ref string) string {
+	if !strings.Contains(ref, "/") || strings.HasPrefix(ref, "refs/bisect/") || strings.HasPrefix(ref, "refs/worktree/") || strings.HasPrefix(ref, "refs/rewritten/") {
+		return gitDir
+	}
+	return GitCommonDir(gitDir)
+}
+
+// The state of a local git repository shown in the top bar, read from the .git folder without the git command
+type GitRepositoryInfo struct {
+	Branch           string // Empty when HEAD is detached
+	Commit           string // The commit HEAD points to, empty in a repository without commits
+	Upstream         string // The ref of the upstream branch, like "refs/remotes/origin/main". Empty if there is none
+	Ahead            int    // Commits not in the upstream branch
+	Behind           int    // Commits in the upstream branch, but not in ours
+	AheadBehindKnown bool   // False if the upstream branch doesn't exist, or there were too many commits to count
+	Stashes          int
+	Operation        string // An operation in progress like "MERGING" or "REBASING", empty if none
+}
+
+// Reads the target of a symbolic ref like HEAD, returns false if it's not a symbolic ref
+func readGitSymbolicRef(gitDir, ref string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(gitRefDir(gitDir, ref), filepath.FromSlash(ref)))
+	if err != nil {
+		return "", false
+	}
+
+	target, found := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: ")
+	return target, found
+}
+
+// Returns the refs in the packed-refs file, which git uses instead of files in .git/refs for most refs
+func readGitPackedRefs(gitDir string) map[string]string {
+	refs := make(map[string]string)
+
+	file, err := os.Open(filepath.Join(GitCommonDir(gitDir), "packed-refs"))
+	if err != nil {
+		return refs
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Comments, and the commits of annotated tags on lines starting with '^'
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+			continue
+		}
+
+		hash, ref, found := strings.Cut(line, " ")
+		if found {
+			refs[ref] = hash
+		}
+	}
+
+	return refs
+}
+
+// Returns the commit hash of ref, following symbolic refs
+func ResolveGitRef(gitDir, ref string) (string, error) {
+	// Symbolic refs can point to each other, but not this deep
+	for i := 0; i < 10; i++ {
+		data, err := os.ReadFile(filepath.Join(gitRefDir(gitDir, ref), filepath.FromSlash(ref)))
+		if err != nil {
+			hash, ok := readGitPackedRefs(gitDir)[ref]
+			if !ok {
+				return "", errors.New("Unable to resolve git ref " + ref)
+			}
+			return hash, nil
+		}
+
+		content := strings.TrimSpace(string(data))
+		target, isSymbolic := strings.CutPrefix(content, "ref: ")
+		if !isSymbolic {
+			return content, nil
+		}
+		ref = target
+	}
+
+	return "", errors.New("Too many levels of symbolic refs in " + ref)
+}
+
+// Returns the upstream ref of branch from the git config, like "refs/remotes/origin/main", or an empty string if it has none.
+// Only reads the config of the repository, not the global config or included files
+func gitBranchUpstream(gitDir, branch string) string {
+	file, err := os.Open(filepath.Join(GitCommonDir(gitDir), "config"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	var remote, merge string
+	inBranchSection := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inBranchSection = line == "[branch \""+branch+"\"]"
+			continue
+		}
+
+		if !inBranchSection {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "remote":
+			remote = strings.TrimSpace(value)
+		case "merge":
+			merge = strings.TrimSpace(value)
+		}
+	}
+
+	if remote == "" || merge == "" {
+		return ""
+	}
+
+	// A local branch as the upstream
+	if remote == "." {
+		return merge
+	}
+
+	return "refs/remotes/" + remote + "/" + strings.TrimPrefix(merge, "refs/heads/")
+}
+
+// Returns the operation in progress like "MERGING", and for rebases the branch being rebased since HEAD is detached
+func gitOperationInProgress(gitDir string) (operation, branch string) {
+	exists := func(name string) bool {
+		_, err := os.Lstat(filepath.Join(gitDir, name))
+		return err == nil
+	}
+
+	for _, rebaseDir := range []string{"rebase-merge", "rebase-apply"} {
+		if !exists(rebaseDir) {
+			continue
+		}
+
+		headName, err := os.ReadFile(filepath.Join(gitDir, rebaseDir, "head-name"))
+		if err == nil {
+			branch = strings.TrimPrefix(strings.TrimSpace(string(headName)), "refs/heads/")
+		}
+
+		// "git am" also uses the rebase-apply folder
+		if rebaseDir == "rebase-apply" && exists(filepath.Join(rebaseDir, "applying")) {
+			return "AM", branch
+		}
+		return "REBASING", branch
+	}
+
+	switch {
+	case exists("MERGE_HEAD"):
+		return "MERGING", ""
+	case exists("CHERRY_PICK_HEAD"):
+		return "CHERRY-PICKING", ""
+	case exists("REVERT_HEAD"):
+		return "REVERTING", ""
+	case exists("BISECT_LOG"):
+		return "BISECTING", ""
+	}
+
+	return "", ""
+}
+
+// Returns the number of stashes, one line per stash in the reflog of refs/stash
+func gitStashCount(gitDir string) int {
+	data, err := os.ReadFile(filepath.Join(GitCommonDir(gitDir), "logs", "refs", "stash"))
+	if err != nil {
+		return 0
+	}
+
+	return strings.Count(string(data), "\n")
+}
+
+// Reads the state of the repository whose .git folder is gitDir
+func ReadGitRepositoryInfo(gitDir string) GitRepositoryInfo {
+	var info GitRepositoryInfo
+
+	headTarget, onBranch := readGitSymbolicRef(gitDir, "HEAD")
+	if onBranch {
+		info.Branch = strings.TrimPrefix(headTarget, "refs/heads/")
+	}
+
+	info.Commit, _ = ResolveGitRef(gitDir, "HEAD")
+
+	var rebasedBranch string
+	info.Operation, rebasedBranch = gitOperationInProgress(gitDir)
+	if info.Branch == "" && rebasedBranch != "" {
+		info.Branch = rebasedBranch
+	}
+
+	info.Stashes = gitStashCount(gitDir)
+
+	if info.Branch == "" || info.Commit == "" {
+		return info
+	}
+
+	info.Upstream = gitBranchUpstream(gitDir, info.Branch)
+	if info.Upstream == "" {
+		return info
+	}
+
+	upstreamCommit, err := ResolveGitRef(gitDir, info.Upstream)
+	if err != nil {
+		return info
+	}
+
+	objects := NewGitObjectReader(GitCommonDir(gitDir))
+	defer objects.Close()
+
+	info.Ahead, info.Behind, err = objects.AheadBehind(info.Commit, upstreamCommit)
+	info.AheadBehindKnown = err == nil
+	return info
+}
+
+// Returns a string which changes when the files ReadGitRepositoryInfo() reads change.
+// Git replaces refs by renaming a new file over them, so their modification times are enough
+func gitRepositoryInfoStamp(gitDir string) string {
+	commonDir := GitCommonDir(gitDir)
+	paths := []string{filepath.Join(gitDir, "HEAD"), filepath.Join(commonDir, "config"), filepath.Join(commonDir, "packed-refs"), filepath.Join(commonDir, "logs", "refs", "stash")}
+	for _, name := range []string{"MERGE_HEAD", "CHERRY_PICK_HEAD", "REVERT_HEAD", "BISECT_LOG", "rebase-merge", "rebase-apply"} {
+		paths = append(paths, filepath.Join(gitDir, name))
+	}
+
+	headTarget, onBranch := readGitSymbolicRef(gitDir, "HEAD")
+	if onBranch {
+		paths = append(paths, filepath.Join(gitRefDir(gitDir, headTarget), filepath.FromSlash(headTarget)))
+
+		upstream := gitBranchUpstream(gitDir, strings.TrimPrefix(headTarget, "refs/heads/"))
+		if upstream != "" {
+			paths = append(paths, filepath.Join(gitRefDir(gitDir, upstream), filepath.FromSlash(upstream)))
+		}
+	}
+
+	var stamp strings.Builder
+	for _, path := range paths {
+		stat, err := os.Lstat(path)
+		if err != nil {
+			stamp.WriteString("-,")
+			continue
+		}
+		stamp.WriteString(strconv.FormatInt(stat.ModTime().UnixNano(), 10) + ":" + strconv.FormatInt(stat.Size(), 10) + ",")
+	}
+
+	return stamp.String()
+}
+
+type cachedGitRepositoryInfo struct {
+	info  GitRepositoryInfo
+	stamp string
+}
+
+// Reads the state of the repository at repositoryPath again if it has changed, the git status handler does this after every git status.
+// This can take a while, since counting the commits ahead/behind reads through the history
+func (gsh *GitStatusHandler) updateRepositoryInfo(repositoryPath string) {
+	gitDir, err := GitDir(repositoryPath)
+	if err != nil {
+		return
+	}
+
+	stamp := gitRepositoryInfoStamp(gitDir)
+
+	gsh.trackedLocalGitReposMutex.Lock()
+	cached, ok := gsh.repositoryInfos[repositoryPath]
+	gsh.trackedLocalGitReposMutex.Unlock()
+	if ok && stamp == cached.stamp {
+		return
+	}
+
+	info := ReadGitRepositoryInfo(gitDir)
+
+	gsh.trackedLocalGitReposMutex.Lock()
+	defer gsh.trackedLocalGitReposMutex.Unlock()
+
+	// We only remember the repositories recently shown, so this can't grow forever
+	if gsh.repositoryInfos == nil || len(gsh.repositoryInfos) > 50 {
+		gsh.repositoryInfos = make(map[string]*cachedGitRepositoryInfo)
+	}
+	gsh.repositoryInfos[repositoryPath] = &cachedGitRepositoryInfo{info: info, stamp: stamp}
+}
+
+// Returns the state of the repository at repositoryPath as of the last git status in it, without reading anything
+func (gsh *GitStatusHandler) RepositoryInfo(repositoryPath string) GitRepositoryInfo {
+	gsh.trackedLocalGitReposMutex.Lock()
+	defer gsh.trackedLocalGitReposMutex.Unlock()
+
+	cached, ok := gsh.repositoryInfos[repositoryPath]
+	if !ok {
+		return GitRepositoryInfo{}
+	}
+	return cached.info
+}
+
+// Returns the text shown in the top bar, like "main ↑1 ↓2 +*"
+// The symbols after the branch are for staged (+), unstaged (*), untracked (?) and conflicted (!) files from the git status
+func GitRepositoryInfoText(info GitRepositoryInfo, state GitFileState) string {
+	var text string
+	switch {
+	case info.Branch != "":
+		text = "[blue::b]" + tview.Escape(info.Branch) + "[-::-]"
+	case info.Commit != "":
+		text = "[blue::b]" + info.Commit[:min(7, len(info.Commit))] + "[-::-] (detached)"
+	default:
+		return ""
+	}
+
+	if info.AheadBehindKnown {
+		if info.Ahead > 0 {
+			text += " ↑" + strconv.Itoa(info.Ahead)
+		}
+		if info.Behind > 0 {
+			text += " ↓" + strconv.Itoa(info.Behind)
+		}
+	}
+
+	symbols := ""
+	if state&GIT_STAGED != 0 {
+		symbols += "[green]+[-]"
+	}
+	if state&GIT_UNSTAGED != 0 {
+		symbols += "[maroon]*[-]"
+	}
+	if state&GIT_UNTRACKED != 0 {
+		symbols += "[olive]?[-]"
+	}
+	if state&GIT_CONFLICTED != 0 {
+		symbols += "[red::b]![-::-]"
+	}
+	if symbols != "" {
+		text += " " + symbols
+	}
+
+	if info.Stashes > 0 {
+		text += " [::d]" + strconv.Itoa(info.Stashes) + " stashed[::-]"
+	}
+
+	if info.Operation != "" {
+		text += " [red::b]" + info.Operation + "[-::-]"
+	}
+
+	return text
+}
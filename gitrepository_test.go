@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func testGit(t *testing.T, repositoryPath string, args ...string) string {
+	// The commits need an author, and the default branch name varies between git versions.
+	// Local submodules aren't allowed by default since git 2.38.1
+	args = append([]string{"-c", "user.name=fen", "-c", "user.email=fen@example.com", "-c", "init.defaultBranch=main", "-c", "gc.auto=0", "-c", "protocol.file.allow=always"}, args...)
+	output, err := RunGit(repositoryPath, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// Commits a new file with its name as the contents
+func testGitCommit(t *testing.T, repositoryPath, filename string) {
+	err := os.WriteFile(filepath.Join(repositoryPath, filename), []byte(filename), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGit(t, repositoryPath, "add", filename)
+	testGit(t, repositoryPath, "commit", "--quiet", "-m", filename)
+}
+
+func TestReadGitRepositoryInfo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	git := func(repositoryPath string, args ...string) string {
+		return testGit(t, repositoryPath, args...)
+	}
+	commit := func(repositoryPath, filename string) {
+		testGitCommit(t, repositoryPath, filename)
+	}
+
+	upstreamPath := t.TempDir()
+	git(upstreamPath, "init", "--quiet")
+	commit(upstreamPath, "a")
+	commit(upstreamPath, "b")
+
+	clonePath := filepath.Join(t.TempDir(), "clone")
+	git(upstreamPath, "clone", "--quiet", upstreamPath, clonePath)
+
+	commit(upstreamPath, "c")
+	git(clonePath, "fetch", "--quiet")
+	commit(clonePath, "d")
+	commit(clonePath, "e")
+
+	check := func(when string) {
+		info := ReadGitRepositoryInfo(filepath.Join(clonePath, ".git"))
+		if info.Branch != "main" {
+			t.Fatalf(when + ": Expected branch \"main\", but got \"" + info.Branch + "\"")
+		}
+		if info.Commit != git(clonePath, "rev-parse", "HEAD") {
+			t.Fatalf(when + ": Expected the commit of HEAD, but got \"" + info.Commit + "\"")
+		}
+		if info.Upstream != "refs/remotes/origin/main" {
+			t.Fatalf(when + ": Expected upstream \"refs/remotes/origin/main\", but got \"" + info.Upstream + "\"")
+		}
+
+		expected := git(clonePath, "rev-list", "--left-right", "--count", "HEAD...@{upstream}")
+		got := strconv.Itoa(info.Ahead) + "\t" + strconv.Itoa(info.Behind)
+		if !info.AheadBehindKnown || got != expected {
+			t.Fatalf(when + ": Expected ahead/behind \"" + expected + "\", but got \"" + got + "\"")
+		}
+	}
+
+	check("Loose objects")
+
+	// Moves the objects and refs into packfiles and packed-refs
+	git(clonePath, "gc", "--quiet", "--aggressive")
+	check("Packed objects")
+
+	err := os.WriteFile(filepath.Join(clonePath, "e"), []byte("changed"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	git(clonePath, "stash", "--quiet")
+	if stashes := ReadGitRepositoryInfo(filepath.Join(clonePath, ".git")).Stashes; stashes != 1 {
+		t.Fatalf("Expected 1 stash, but got " + strconv.Itoa(stashes))
+	}
+
+	git(clonePath, "checkout", "--quiet", "--detach", "HEAD~1")
+	info := ReadGitRepositoryInfo(filepath.Join(clonePath, ".git"))
+	if info.Branch != "" || info.Commit != git(clonePath, "rev-parse", "HEAD") {
+		t.Fatalf("Expected a detached HEAD, but got branch \"" + info.Branch + "\" at \"" + info.Commit + "\"")
+	}
+}
+
+func TestGitRepositoryLayouts(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mainPath := filepath.Join(tempDir, "main")
+	libraryPath := filepath.Join(tempDir, "library")
+	worktreePath := filepath.Join(tempDir, "worktree")
+	barePath := filepath.Join(tempDir, "bare.git")
+	submodulePath := filepath.Join(mainPath, "submodule")
+
+	for _, path := range []string{mainPath, libraryPath} {
+		err := os.Mkdir(path, 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testGit(t, path, "init", "--quiet")
+		testGitCommit(t, path, "file")
+	}
+
+	testGit(t, mainPath, "submodule", "--quiet", "add", libraryPath, "submodule")
+	testGit(t, mainPath, "commit", "--quiet", "-m", "Add submodule")
+	testGit(t, mainPath, "worktree", "add", "--quiet", "-b", "feature", worktreePath)
+	testGit(t, tempDir, "clone", "--quiet", "--bare", mainPath, barePath)
+
+	gsh := GitStatusHandler{}
+	expectedRepositories := map[string]string{
+		filepath.Join(mainPath, "file"):          mainPath,
+		filepath.Join(submodulePath, "file"):     submodulePath,
+		submodulePath:                            submodulePath,
+		filepath.Join(worktreePath, "file"):      worktreePath,
+		filepath.Join(barePath, "refs", "heads"): barePath,
+	}
+	for path, expected := range expectedRepositories {
+		got, err := gsh.TryFindParentGitRepository(path)
+		if err != nil || got != expected {
+			t.Fatalf("Expected the repository of \"" + path + "\" to be \"" + expected + "\", but got \"" + got + "\"")
+		}
+	}
+
+	expectedGitDirs := map[string]string{
+		mainPath:      filepath.Join(mainPath, ".git"),
+		submodulePath: filepath.Join(mainPath, ".git", "modules", "submodule"),
+		worktreePath:  filepath.Join(mainPath, ".git", "worktrees", "worktree"),
+		barePath:      barePath,
+	}
+	for repositoryPath, expected := range expectedGitDirs {
+		got, err := GitDir(repositoryPath)
+		if err != nil || got != expected {
+			t.Fatalf("Expected the git directory of \"" + repositoryPath + "\" to be \"" + expected + "\", but got \"" + got + "\"")
+		}
+	}
+
+	worktreeGitDir := expectedGitDirs[worktreePath]
+	if GitCommonDir(worktreeGitDir) != filepath.Join(mainPath, ".git") {
+		t.Fatalf("Expected the worktree to share the refs of the main repository, but got \"" + GitCommonDir(worktreeGitDir) + "\"")
+	}
+
+	info := ReadGitRepositoryInfo(worktreeGitDir)
+	if info.Branch != "feature" || info.Commit != testGit(t, worktreePath, "rev-parse", "HEAD") {
+		t.Fatalf("Expected the worktree to be on branch \"feature\", but got \"" + info.Branch + "\" at \"" + info.Commit + "\"")
+	}
+
+	info = ReadGitRepositoryInfo(barePath)
+	if info.Branch != "main" || info.Commit != testGit(t, mainPath, "rev-parse", "HEAD") {
+		t.Fatalf("Expected the bare repository to be on branch \"main\", but got \"" + info.Branch + "\" at \"" + info.Commit + "\"")
+	}
+
+	// A change in the submodule belongs to the submodule, the main repository only sees that the submodule changed
+	err = os.WriteFile(filepath.Join(submodulePath, "file"), []byte("changed"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	submoduleStates, err := GitStatus(context.Background(), submodulePath)
+	if err != nil || submoduleStates["file"] != GIT_MODIFIED {
+		t.Fatalf("Expected the file in the submodule to be modified, but got %v, %v", submoduleStates, err)
+	}
+
+	mainStates, err := GitStatus(context.Background(), mainPath)
+	if err != nil || mainStates["submodule"] != GIT_MODIFIED {
+		t.Fatalf("Expected the submodule to be modified in the main repository, but got %v, %v", mainStates, err)
+	}
+	if _, ok := mainStates[filepath.Join("submodule", "file")]; ok {
+		t.Fatalf("Expected the file in the submodule not to be attributed to the main repository")
+	}
+
+	_, err = GitStatus(context.Background(), barePath)
+	if err == nil {
+		t.Fatalf("Expected an error running git status in a bare repository")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"cmp"
+	"math/bits"
+	"slices"
+	"time"
+)
+
+// A rough estimate of the memory used by an entry in ChangedFileState.changedFiles, not counting the path itself.
+// Includes the string header, the GitFileState and the map overhead per entry
+const gitStatusEntryOverheadBytes = 48
+
+// Returns an estimate of the memory used by changedFiles in bytes
+func gitStatusMemoryUsage(changedFiles map[string]GitFileState) int64 {
+	var bytes int64
+	for path := range changedFiles {
+		bytes += int64(len(path)) + gitStatusEntryOverheadBytes
+	}
+	return bytes
+}
+
+// Even within the memory budget, we don't remember more repositories than this, since clean ones use almost no memory
+const gitStatusCacheMaxRepositories = 50
+
+// Returns how slow the git status of repo was, rounded to powers of 2 in milliseconds, since the durations vary between runs
+func gitStatusDurationClass(repo *ChangedFileState) int {
+	return bits.Len64(uint64(max(repo.statusDuration.Milliseconds(), 0)))
+}
+
+// Compares how worth it repositories are to forget, negative if a should be evicted before b.
+// Repositories that are quick to run a git status on are evicted first, so one that takes seconds stays cached over many small ones.
+// Between repositories about as quick, the ones using the most memory, and then the ones not looked at in the longest, are evicted first
+func compareGitStatusEviction(a, b *ChangedFileState) int {
+	if c := cmp.Compare(gitStatusDurationClass(a), gitStatusDurationClass(b)); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(b.memoryBytes, a.memoryBytes); c != 0 {
+		return c
+	}
+	return a.lastUsed.Compare(b.lastUsed)
+}
+
+// Removes repositories from repos until their memory usage is within budgetBytes, and there are at most gitStatusCacheMaxRepositories of them.
+// Never removes keep. Returns the paths of the removed repositories
+func evictGitStatusCache(repos map[string]*ChangedFileState, budgetBytes int64, keep string) []string {
+	var total int64
+	for _, repo := range repos {
+		total += repo.memoryBytes
+	}
+
+	var evicted []string
+	for total > budgetBytes || len(repos) > gitStatusCacheMaxRepositories {
+		worstPath := ""
+		for path, repo := range repos {
+			if path == keep {
+				continue
+			}
+
+			if worstPath == "" || compareGitStatusEviction(repo, repos[worstPath]) < 0 {
+				worstPath = path
+			}
+		}
+
+		if worstPath == "" {
+			break
+		}
+
+		total -= repos[worstPath].memoryBytes
+		delete(repos, worstPath)
+		evicted = append(evicted, worstPath)
+	}
+
+	return evicted
+}
+
+type GitStatusCacheEntry struct {
+	RepositoryPath string
+	ChangedFiles   int
+	MemoryBytes    int64
+	StatusDuration time.Duration
+	LastChecked    time.Time
+	LastUsed       time.Time
+}
+
+// Returns the repositories in the git status cache sorted by memory usage, biggest first
+func (gsh *GitStatusHandler) CacheStats() []GitStatusCacheEntry {
+	gsh.trackedLocalGitReposMutex.Lock()
+	defer gsh.trackedLocalGitReposMutex.Unlock()
+
+	entries := make([]GitStatusCacheEntry, 0, len(gsh.trackedLocalGitRepos))
+	for path, repo := range gsh.trackedLocalGitRepos {
+		entries = append(entries, GitStatusCacheEntry{
+			RepositoryPath: path,
+			ChangedFiles:   len(repo.changedFiles),
+			MemoryBytes:    repo.memoryBytes,
+			StatusDuration: repo.statusDuration,
+			LastChecked:    repo.lastChecked,
+			LastUsed:       repo.lastUsed,
+		})
+	}
+
+	slices.SortFunc(entries, func(a, b GitStatusCacheEntry) int {
+		return cmp.Compare(b.MemoryBytes, a.MemoryBytes)
+	})
+	return entries
+}
+
+// Returns the number of repositories evicted from the git status cache so far
+func (gsh *GitStatusHandler) CacheEvictions() int {
+	gsh.trackedLocalGitReposMutex.Lock()
+	defer gsh.trackedLocalGitReposMutex.Unlock()
+
+	return gsh.evictions
+}
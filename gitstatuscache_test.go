@@ -0,0 +1,70 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEvictGitStatusCache(t *testing.T) {
+	now := time.Now()
+	newRepos := func() map[string]*ChangedFileState {
+		return map[string]*ChangedFileState{
+			// Takes seconds to git status, should be kept over the small ones
+			"/monorepo": {memoryBytes: 600, statusDuration: 5 * time.Second, lastUsed: now.Add(-time.Minute)},
+			"/small1":   {memoryBytes: 300, statusDuration: 10 * time.Millisecond, lastUsed: now.Add(-time.Minute)},
+			"/small2":   {memoryBytes: 300, statusDuration: 10 * time.Millisecond, lastUsed: now.Add(-2 * time.Minute)},
+			"/current":  {memoryBytes: 400, statusDuration: time.Millisecond, lastUsed: now.Add(-time.Hour)},
+		}
+	}
+
+	repos := newRepos()
+	evicted := evictGitStatusCache(repos, 1400, "/current")
+	if !slices.Equal(evicted, []string{"/small2"}) {
+		t.Fatalf("Expected only \"/small2\" to be evicted, but got %v", evicted)
+	}
+
+	repos = newRepos()
+	evicted = evictGitStatusCache(repos, 0, "/current")
+	if len(repos) != 1 || repos["/current"] == nil {
+		t.Fatalf("Expected only \"/current\" to be kept, but got " + strconv.Itoa(len(repos)) + " repositories")
+	}
+	if !slices.Equal(evicted, []string{"/small2", "/small1", "/monorepo"}) {
+		t.Fatalf("Expected the monorepo to be evicted last, but got %v", evicted)
+	}
+
+	repos = newRepos()
+	evicted = evictGitStatusCache(repos, 1600, "")
+	if len(evicted) != 0 {
+		t.Fatalf("Expected nothing to be evicted within the budget, but got %v", evicted)
+	}
+
+	// A slow repository is kept even when it uses far more memory and hasn't been looked at in a while
+	repos = map[string]*ChangedFileState{
+		"/monorepo": {memoryBytes: 1000000, statusDuration: 5 * time.Second, lastUsed: now.Add(-time.Hour)},
+		"/small":    {memoryBytes: 1000, statusDuration: 10 * time.Millisecond, lastUsed: now.Add(-time.Minute)},
+	}
+	evicted = evictGitStatusCache(repos, 1000000, "")
+	if !slices.Equal(evicted, []string{"/small"}) {
+		t.Fatalf("Expected only \"/small\" to be evicted, but got %v", evicted)
+	}
+
+	// Clean repositories use almost no memory, but there can't be too many of them
+	repos = make(map[string]*ChangedFileState)
+	for i := 0; i < gitStatusCacheMaxRepositories+5; i++ {
+		repos["/clean"+strconv.Itoa(i)] = &ChangedFileState{statusDuration: time.Millisecond, lastUsed: now.Add(time.Duration(i) * time.Second)}
+	}
+	evicted = evictGitStatusCache(repos, 1000000, "")
+	if len(repos) != gitStatusCacheMaxRepositories || !slices.Equal(evicted, []string{"/clean0", "/clean1", "/clean2", "/clean3", "/clean4"}) {
+		t.Fatalf("Expected the 5 least recently used repositories to be evicted, but got %v", evicted)
+	}
+}
+
+func TestGitStatusMemoryUsage(t *testing.T) {
+	got := gitStatusMemoryUsage(map[string]GitFileState{"a": GIT_MODIFIED, "folder/b": GIT_UNTRACKED})
+	expected := int64(len("a") + len("folder/b") + 2*gitStatusEntryOverheadBytes)
+	if got != expected {
+		t.Fatalf("Expected " + strconv.FormatInt(expected, 10) + " bytes, but got " + strconv.FormatInt(got, 10))
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Debug view of the git status cache, showing which repositories are remembered and what they cost
+type GitStatusCacheScreen struct {
+	*tview.Box
+	fen          *Fen
+	scrollOffset int
+}
+
+func NewGitStatusCacheScreen(fen *Fen) *GitStatusCacheScreen {
+	return &GitStatusCacheScreen{
+		Box: tview.NewBox().SetBackgroundColor(tcell.ColorDefault),
+		fen: fen,
+	}
+}
+
+func (g *GitStatusCacheScreen) Scroll(amount int) {
+	g.scrollOffset = max(0, g.scrollOffset+amount)
+}
+
+func (g *GitStatusCacheScreen) Draw(screen tcell.Screen) {
+	x, y, w, h := g.GetInnerRect()
+	g.Box.SetRect(x, y+1, w, h-2)
+	g.Box.DrawForSubclass(screen, g)
+	y++
+	h -= 2
+
+	tview.Print(screen, "[::r] Git status cache [::-]", x, y, w, tview.AlignLeft, tcell.ColorDefault)
+
+	helpText := "[::d]j/k: scroll  q: close"
+	tview.Print(screen, helpText, x, y+h-1, w, tview.AlignLeft, tcell.ColorDefault)
+
+	if !g.fen.config.GitStatus {
+		tview.Print(screen, "[:red]git_status is disabled in the config", x+1, y+2, w, tview.AlignLeft, tcell.ColorDefault)
+		return
+	}
+
+	entries := g.fen.gitStatusHandler.CacheStats()
+
+	var totalBytes int64
+	for _, e := range entries {
+		totalBytes += e.MemoryBytes
+	}
+
+	bytesText := func(bytes int64) string {
+		return BytesToFileSizeFormat(uint64(bytes), 2, g.fen.config.FileSizeFormat)
+	}
+
+	budgetBytes := int64(max(g.fen.config.GitStatusCacheMegabytes, 0)) * 1024 * 1024
+	status := bytesText(totalBytes) + " of " + bytesText(budgetBytes) + ", " + strconv.Itoa(len(entries)) + " repositories, " + strconv.Itoa(g.fen.gitStatusHandler.CacheEvictions()) + " evicted"
+	tview.Print(screen, status, x, y, w, tview.AlignRight, tcell.ColorDefault)
+
+	const columnWidth = 12
+	header := []string{"Memory", "Files", "Status took", "Checked", "Used"}
+	for i, text := range header {
+		tview.Print(screen, "[::b]"+text, x+1+i*columnWidth, y+2, columnWidth, tview.AlignLeft, tcell.ColorDefault)
+	}
+	tview.Print(screen, "[::b]Repository", x+1+len(header)*columnWidth, y+2, w, tview.AlignLeft, tcell.ColorDefault)
+
+	if len(entries) == 0 {
+		tview.Print(screen, "[::d]No repositories with changed files", x+1, y+3, w, tview.AlignLeft, tcell.ColorDefault)
+		return
+	}
+
+	ago := func(t time.Time) string {
+		return time.Since(t).Round(time.Second).String() + " ago"
+	}
+
+	listHeight := max(1, h-5)
+	g.scrollOffset = min(g.scrollOffset, max(0, len(entries)-listHeight))
+	for i, e := range entries[g.scrollOffset:min(len(entries), g.scrollOffset+listHeight)] {
+		columns := []string{
+			bytesText(e.MemoryBytes),
+			strconv.Itoa(e.ChangedFiles),
+			e.StatusDuration.Round(time.Millisecond).String(),
+			ago(e.LastChecked),
+			ago(e.LastUsed),
+		}
+
+		for j, text := range columns {
+			tview.Print(screen, text, x+1+j*columnWidth, y+3+i, columnWidth, tview.AlignLeft, tcell.ColorDefault)
+		}
+		tview.Print(screen, tview.Escape(e.RepositoryPath), x+1+len(columns)*columnWidth, y+3+i, w, tview.AlignLeft, tcell.ColorDefault)
+	}
+}
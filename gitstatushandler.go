@@ -8,10 +8,10 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
-	"github.com/kivattt/gogitstatus"
 	"github.com/rivo/tview"
 )
 
@@ -26,52 +26,109 @@ type GitStatusHandler struct {
 
 	gitIndexFileWatcher *fsnotify.Watcher
 
+	watchedRepository      string // The repository whose git directory gitIndexFileWatcher watches
+	watchedRepositoryMutex sync.Mutex
+
 	repoPathCurrentlyWorkingOn string // Does not require a mutex due to workerWaitGroup
 
-	trackedLocalGitRepos      map[string]ChangedFileState
+	trackedLocalGitRepos      map[string]*ChangedFileState
 	trackedLocalGitReposMutex sync.Mutex
+	evictions                 int                                 // How many repositories have been removed from trackedLocalGitRepos to stay within the memory budget
+	repositoryInfos           map[string]*cachedGitRepositoryInfo // The keys are repository paths, see RepositoryInfo()
+
+	statusGeneration atomic.Uint64 // Increased every time a git status finishes, see StatusGeneration()
 }
 
 type ChangedFileState struct {
-	changedFiles map[string]gogitstatus.ChangedFile
+	changedFiles map[string]GitFileState // Relative to the repository, including folders
+	hasIgnored   bool                    // Files inside ignored folders aren't in changedFiles, so we only look for them if there are any
 	lastChecked  time.Time
+
+	statusDuration time.Duration // How long the last git status took, slow repositories are kept around longer
+	combinedState  GitFileState  // The states of every file combined, without ignored files, see RepositoryState()
+	memoryBytes    int64         // Estimated memory used by changedFiles
+	lastUsed       time.Time     // Last time the state was looked up
 }
 
-// Returns an error if path is not inside a tracked local git repository
+// Returns an error if path is not inside a tracked local git repository.
+// Only the repository closest to path is checked, so files in a submodule aren't attributed to the repository containing it
 func (gsh *GitStatusHandler) TryFindTrackedParentGitRepository(path string) (string, error) {
+	repositoryPath, err := gsh.TryFindParentGitRepository(path)
+	if err != nil {
+		return "", err
+	}
+
 	gsh.trackedLocalGitReposMutex.Lock()
 	defer gsh.trackedLocalGitReposMutex.Unlock()
 
-	split := SplitPath(path)
-	for _, pathSplit := range split {
-		_, ok := gsh.trackedLocalGitRepos[pathSplit]
-		if ok {
-			return pathSplit, nil
-		}
+	repo, ok := gsh.trackedLocalGitRepos[repositoryPath]
+	if !ok {
+		return "", errors.New("Path is not in any tracked git repositories")
 	}
+	repo.lastUsed = time.Now()
 
-	return "", errors.New("Path is not in any tracked git repositories")
+	return repositoryPath, nil
 }
 
-// Looks for the parent directory of path (or path itself), closest to root, containing a ".git" directory.
+// Looks for the parent directory of path (or path itself), closest to path, which is a local Git repository.
+// That is a directory containing a ".git" directory, a ".git" file pointing to the git directory of a worktree or submodule, or a bare repository.
 // Returns an error if none found.
 func (gsh *GitStatusHandler) TryFindParentGitRepository(path string) (string, error) {
 	split := SplitPath(path)
-	for _, pathSplit := range split {
-		stat, err := os.Lstat(filepath.Join(pathSplit, ".git"))
-		if err == nil && stat.IsDir() {
-			return pathSplit, nil
+	for i := len(split) - 1; i >= 0; i-- {
+		stat, err := os.Lstat(filepath.Join(split[i], ".git"))
+		if err == nil {
+			if stat.IsDir() {
+				return split[i], nil
+			}
+
+			_, err := readGitDirFile(filepath.Join(split[i], ".git"))
+			if err == nil {
+				return split[i], nil
+			}
+		}
+
+		if IsBareGitRepository(split[i]) {
+			return split[i], nil
 		}
 	}
 
 	return "", errors.New("path is not in a local Git repository")
 }
 
-// Returns true if path is an unstaged/untracked file in the local Git repository at repositoryPath.
+// Watches the index file of the repository at repositoryPath instead of the previous one, so we update in real-time on "git add" / "git restore"
+func (gsh *GitStatusHandler) WatchRepository(repositoryPath string) {
+	gitDir, err := GitDir(repositoryPath)
+	if err != nil {
+		return
+	}
+
+	gsh.watchedRepositoryMutex.Lock()
+	defer gsh.watchedRepositoryMutex.Unlock()
+
+	if repositoryPath == gsh.watchedRepository {
+		return
+	}
+
+	// Remove previous watched path
+	for _, e := range gsh.gitIndexFileWatcher.WatchList() {
+		gsh.gitIndexFileWatcher.Remove(e)
+	}
+
+	// Watching the git directory instead of the index file, since git replaces the index by renaming "index.lock" over it
+	err = gsh.gitIndexFileWatcher.Add(gitDir)
+	if err != nil {
+		gsh.watchedRepository = ""
+		return
+	}
+	gsh.watchedRepository = repositoryPath
+}
+
+// Returns the git state of path in the local Git repository at repositoryPath, 0 if it is unchanged.
 // Takes in absolute paths (panics when either are non-absolute).
-func (gsh *GitStatusHandler) PathIsUnstagedOrUntracked(path, repositoryPath string) bool {
+func (gsh *GitStatusHandler) PathGitState(path, repositoryPath string) GitFileState {
 	if !filepath.IsAbs(path) || !filepath.IsAbs(repositoryPath) {
-		panic("AbsolutePathIsUnstagedOrUntracked received a non-absolute path")
+		panic("PathGitState received a non-absolute path")
 	}
 
 	gsh.trackedLocalGitReposMutex.Lock()
@@ -79,20 +136,36 @@ func (gsh *GitStatusHandler) PathIsUnstagedOrUntracked(path, repositoryPath stri
 
 	repo, repoOk := gsh.trackedLocalGitRepos[repositoryPath]
 	if !repoOk {
-		return false
+		return 0
 	}
 
 	// TODO: Improve performance? filepath.Rel() seems a little slow
 	relativePathToRepo, err := filepath.Rel(repositoryPath, path)
 	if err != nil {
-		return false
+		return 0
+	}
+
+	state, ok := repo.changedFiles[relativePathToRepo]
+	if ok || !repo.hasIgnored {
+		return state
+	}
+
+	// Ignored folders are listed without the files inside them
+	for parent := filepath.Dir(relativePathToRepo); parent != "."; parent = filepath.Dir(parent) {
+		if repo.changedFiles[parent]&GIT_IGNORED != 0 {
+			return GIT_IGNORED
+		}
 	}
 
-	_, pathUnstagedOrUntracked := repo.changedFiles[relativePathToRepo]
-	return pathUnstagedOrUntracked
+	return 0
 }
 
-// Returns true if the repository at path contains any unstaged/untracked files
+// Returns a number which changes every time a git status finishes, so results depending on the git state can be cached until then
+func (gsh *GitStatusHandler) StatusGeneration() uint64 {
+	return gsh.statusGeneration.Load()
+}
+
+// Returns true if the repository at path contains any changed or untracked files
 func (gsh *GitStatusHandler) RepositoryPathContainsUnstagedOrUntracked(path string) bool {
 	gsh.trackedLocalGitReposMutex.Lock()
 	defer gsh.trackedLocalGitReposMutex.Unlock()
@@ -102,7 +175,26 @@ func (gsh *GitStatusHandler) RepositoryPathContainsUnstagedOrUntracked(path stri
 		return false
 	}
 
-	return len(repo.changedFiles) > 0
+	for _, state := range repo.changedFiles {
+		if state&^GIT_IGNORED != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the states of every changed, untracked and conflicted file in the repository at path combined, 0 if there are none
+func (gsh *GitStatusHandler) RepositoryState(path string) GitFileState {
+	gsh.trackedLocalGitReposMutex.Lock()
+	defer gsh.trackedLocalGitReposMutex.Unlock()
+
+	repo, repoOk := gsh.trackedLocalGitRepos[path]
+	if !repoOk {
+		return 0
+	}
+	repo.lastUsed = time.Now()
+
+	return repo.combinedState
 }
 
 func (gsh *GitStatusHandler) Init() {
@@ -129,16 +221,14 @@ func (gsh *GitStatusHandler) Init() {
 					continue
 				}
 
-				watchList := gsh.gitIndexFileWatcher.WatchList()
-				if watchList != nil {
-					if len(watchList) > 1 {
-						panic("In GitStatusHandler: Length of watchList exceeded 1")
-					}
+				// The git directory isn't always the ".git" folder in the repository, so we remember which repository it belongs to
+				gsh.watchedRepositoryMutex.Lock()
+				repositoryPath := gsh.watchedRepository
+				gsh.watchedRepositoryMutex.Unlock()
 
-					if len(watchList) == 1 {
-						// TODO: Make this forcefully re-run the StatusWithContext()
-						gsh.channel <- filepath.Dir(watchList[0])
-					}
+				if repositoryPath != "" {
+					// TODO: Make this forcefully re-run the StatusWithContext()
+					gsh.channel <- repositoryPath
 				}
 			case _, ok := <-gsh.gitIndexFileWatcher.Errors:
 				if !ok {
@@ -154,7 +244,7 @@ func (gsh *GitStatusHandler) Init() {
 	gsh.channel = make(chan string, 100)
 
 	gsh.trackedLocalGitReposMutex.Lock()
-	gsh.trackedLocalGitRepos = make(map[string]ChangedFileState)
+	gsh.trackedLocalGitRepos = make(map[string]*ChangedFileState)
 	gsh.trackedLocalGitReposMutex.Unlock()
 
 	go func() {
@@ -174,32 +264,6 @@ func (gsh *GitStatusHandler) Init() {
 				continue chanLoop
 			}
 
-			// TODO: Prefer removing repos that took a short time to StatusWithContext() by storing the time it took.
-			// This will prevent removal of repos that take a long time to git status.
-
-			// Remove oldest tracked repository after 50 repos (pretty arbitrary, changed file storage can vary massively in size)
-			gsh.trackedLocalGitReposMutex.Lock()
-			if len(gsh.trackedLocalGitRepos) > 50 {
-				var oldestRepositoryTime time.Time
-				oldestRepositoryPath := ""
-
-				for k, v := range gsh.trackedLocalGitRepos {
-					if oldestRepositoryPath == "" {
-						oldestRepositoryTime = v.lastChecked
-						oldestRepositoryPath = k
-						continue
-					}
-
-					if v.lastChecked.Before(oldestRepositoryTime) {
-						oldestRepositoryTime = v.lastChecked
-						oldestRepositoryPath = k
-					}
-				}
-
-				delete(gsh.trackedLocalGitRepos, oldestRepositoryPath)
-			}
-			gsh.trackedLocalGitReposMutex.Unlock()
-
 			// Cancel the previous gogitstatus.StatusWithContext()
 			if gsh.cancelFunc != nil {
 				gsh.cancelFunc()
@@ -227,8 +291,14 @@ func (gsh *GitStatusHandler) Init() {
 				gsh.fen.runningGitStatus = true
 				gsh.app.QueueUpdateDraw(func() {})
 
-				changedFiles, err := gogitstatus.StatusWithContext(gsh.ctx, gsh.repoPathCurrentlyWorkingOn)
-				changedFiles = gogitstatus.IncludingDirectories(changedFiles)
+				statusStart := time.Now()
+				changedFiles, err := GitStatus(gsh.ctx, gsh.repoPathCurrentlyWorkingOn)
+				statusDuration := time.Since(statusStart)
+
+				// Also for bare repositories, which have no git status
+				if gsh.ctx.Err() == nil {
+					gsh.updateRepositoryInfo(gsh.repoPathCurrentlyWorkingOn)
+				}
 
 				if err != nil {
 					gsh.fen.runningGitStatus = false // Can't defer this because it has to run before QueueUpdateDraw()
@@ -240,20 +310,37 @@ func (gsh *GitStatusHandler) Init() {
 					gsh.trackedLocalGitReposMutex.Lock()
 					delete(gsh.trackedLocalGitRepos, gsh.repoPathCurrentlyWorkingOn)
 					gsh.trackedLocalGitReposMutex.Unlock()
+					gsh.statusGeneration.Add(1)
 					gsh.fen.runningGitStatus = false // Can't defer this because it has to run before QueueUpdateDraw()
-					gsh.app.QueueUpdateDraw(func() {})
+					gsh.app.QueueUpdateDraw(gsh.fen.refilterChangedFilesOnly)
 					return
 				}
 
+				var combinedState GitFileState
+				for _, state := range changedFiles {
+					combinedState |= state
+				}
+
+				now := time.Now()
 				gsh.trackedLocalGitReposMutex.Lock()
-				gsh.trackedLocalGitRepos[gsh.repoPathCurrentlyWorkingOn] = ChangedFileState{
-					changedFiles: changedFiles,
-					lastChecked:  time.Now(),
+				gsh.trackedLocalGitRepos[gsh.repoPathCurrentlyWorkingOn] = &ChangedFileState{
+					changedFiles:   changedFiles,
+					hasIgnored:     combinedState&GIT_IGNORED != 0,
+					combinedState:  combinedState &^ GIT_IGNORED,
+					lastChecked:    now,
+					statusDuration: statusDuration,
+					memoryBytes:    gitStatusMemoryUsage(changedFiles),
+					lastUsed:       now,
 				}
+
+				budgetBytes := int64(max(gsh.fen.config.GitStatusCacheMegabytes, 0)) * 1024 * 1024
+				evicted := evictGitStatusCache(gsh.trackedLocalGitRepos, budgetBytes, gsh.repoPathCurrentlyWorkingOn)
+				gsh.evictions += len(evicted)
 				gsh.trackedLocalGitReposMutex.Unlock()
+				gsh.statusGeneration.Add(1)
 
 				gsh.fen.runningGitStatus = false // Can't defer this because it has to run before QueueUpdateDraw()
-				gsh.app.QueueUpdateDraw(func() {})
+				gsh.app.QueueUpdateDraw(gsh.fen.refilterChangedFilesOnly)
 			}()
 		}
 		gsh.wg.Done()
@@ -29,12 +29,22 @@ type control struct {
 var helpScreenControlsList = []control{
 	{KeyBindings: []string{"?", "F1"}, Description: "Toggle help menu (you are here!)"},
 	{KeyBindings: []string{"F2"}, Description: "Show libraries used in fen"},
+	{KeyBindings: []string{"F3"}, Description: "Show the git status cache"},
 	{KeyBindings: []string{"q"}, Description: "Quit fen"},
 	{KeyBindings: []string{"o"}, Description: "Options"},
 
 	{KeyBindings: []string{"z", "Backspace"}, Description: "Toggle hidden files"},
+	{KeyBindings: []string{"s"}, Description: "Change the sorting"},
+	{KeyBindings: []string{"S"}, Description: "Toggle reverse sorting"},
+	{KeyBindings: []string{"F"}, Description: "Toggle file list mode (list all files recursively)"},
+	{KeyBindings: []string{"u"}, Description: "Show what is using disk space in the current folder"},
 	{KeyBindings: []string{"^Space", "^B"}, Description: "Open file(s) with specific program"},
 	{KeyBindings: []string{"!"}, Description: "Run system shell command"},
+	{KeyBindings: []string{"+", "-"}, Description: "Stage/unstage file(s) in git"},
+	{KeyBindings: []string{"R"}, Description: "Discard the unstaged changes of file(s) in git"},
+	{KeyBindings: []string{"]", "["}, Description: "Go to the next/previous changed file in the git repository"},
+	{KeyBindings: []string{"*"}, Description: "Toggle showing changed files only"},
+	{KeyBindings: []string{"="}, Description: "Cycle the right pane between the file preview, git diff, git log and git blame"},
 
 	{KeyBindings: []string{"n"}, Description: "Create a new file"},
 	{KeyBindings: []string{"N"}, Description: "Create a new folder"},
@@ -45,14 +55,27 @@ var helpScreenControlsList = []control{
 	{KeyBindings: []string{"b"}, Description: "Bulk-rename files in editor"},
 	{KeyBindings: []string{"Del", "x"}, Description: "Delete file"},
 	{KeyBindings: []string{"/", "^F"}, Description: "Search"},
+	{KeyBindings: []string{".", ","}, Description: "Go to the next/previous search match"},
+	{KeyBindings: []string{"|"}, Description: "Filter the current folder, empty to clear"},
 	{KeyBindings: []string{"f", "^N"}, Description: "Search filenames recursively"},
 	{KeyBindings: []string{"c"}, Description: "Goto path"},
+	{KeyBindings: []string{"J"}, Description: "Jump to a visited folder, ranked by how often and recently you visited it"},
+	{KeyBindings: []string{"^T"}, Description: "Open a new tab"},
+	{KeyBindings: []string{"^W"}, Description: "Close the current tab"},
+	{KeyBindings: []string{"Tab", "Shift+Tab"}, Description: "Go to the next/previous tab, or the other panel in dual-pane mode"},
+	{KeyBindings: []string{"t"}, Description: "Toggle dual-pane mode"},
+	{KeyBindings: []string{"w"}, Description: "Toggle maximizing the middle pane"},
+	{KeyBindings: []string{"<", ">"}, Description: "Make the middle pane narrower/wider"},
+	{KeyBindings: []string{"C"}, Description: "Copy file(s) to the other panel (dual-pane mode)"},
+	{KeyBindings: []string{"m"}, Description: "Move file(s) to the other panel (dual-pane mode)"},
 
 	{KeyBindings: []string{"Home", "g"}, Description: "Go to the top"},
 	{KeyBindings: []string{"End", "G"}, Description: "Go to the bottom"},
 	{KeyBindings: []string{"M"}, Description: "Go to the middle"},
 	{KeyBindings: []string{"Ctrl+Left"}, Description: "Go to the root folder"},
 	{KeyBindings: []string{"Ctrl+Right"}, Description: "Go to the path furthest down in history"},
+	{KeyBindings: []string{"Alt+Left", "Alt+h"}, Description: "Go back to the previous folder"},
+	{KeyBindings: []string{"Alt+Right", "Alt+l"}, Description: "Go forward to the next folder"},
 	{KeyBindings: []string{"PgUp", "PgDn"}, Description: "Scroll up/down an entire page"},
 	{KeyBindings: []string{"H"}, Description: "Go to the top of the screen"},
 	{KeyBindings: []string{"L"}, Description: "Go to the bottom of the screen"},
@@ -62,6 +85,7 @@ var helpScreenControlsList = []control{
 	{KeyBindings: []string{"D"}, Description: "Deselect all, press again to un-yank"},
 	{KeyBindings: []string{"F5"}, Description: "Refresh files, sync screen"},
 	{KeyBindings: []string{"0-9"}, Description: "Go to a configured bookmark"},
+	{KeyBindings: []string{"B"}, Description: "Show named bookmarks, add the current folder as one"},
 }
 
 func (helpScreen *HelpScreen) Draw(screen tcell.Screen) {
@@ -98,6 +98,23 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 			return nil
 		}
 
+		// Alt+h and Alt+l have to be checked before the movement keys
+		if event.Modifiers()&tcell.ModAlt != 0 && (event.Key() == tcell.KeyLeft || event.Key() == tcell.KeyRight || event.Rune() == 'h' || event.Rune() == 'l') {
+			var err error
+			if event.Key() == tcell.KeyLeft || event.Rune() == 'h' {
+				err = fen.GoBack()
+			} else {
+				err = fen.GoForward()
+			}
+
+			if err != nil {
+				fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+			} else {
+				fen.history.AddToHistory(fen.sel)
+			}
+			return nil
+		}
+
 		// Movement/navigation keys
 		wasMovementKey := true
 		if (event.Modifiers()&tcell.ModCtrl == 0 && event.Key() == tcell.KeyLeft) || event.Rune() == 'h' {
@@ -165,24 +182,84 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 		}
 
 		if event.Rune() == '/' || event.Key() == tcell.KeyCtrlF {
+			useRegex := fen.config.SearchRegex
+			searchLabel := func() string {
+				if useRegex {
+					return " Regex search: "
+				}
+				return " Search: "
+			}
+
 			inputField := tview.NewInputField().
-				SetLabel(" Search: ").
-				SetPlaceholder("case-insensitive").
+				SetLabel(searchLabel()).
+				SetPlaceholder("case-" + fen.config.SearchCase + ", Ctrl+T to toggle regex").
 				SetFieldWidth(-1) // Special feature of my tview fork, github.com/kivattt/tview
 
 			inputField.SetAcceptanceFunc(func(textToCheck string, lastChar rune) bool {
 				return lastChar != '/' // FIXME: Hack to prevent the slash appearing in the search inputfield by just disallowing them
 			})
 
+			// Restored when pressing Escape
+			selBeforeSearch := fen.sel
+			searchMatcherBeforeSearch := fen.searchMatcher
+
+			// Search as you type, from the entry selected when the search was opened
+			updateSearch := func(text string) {
+				fen.sel = selBeforeSearch
+				fen.searchMatcher = nil
+				inputField.SetTitle("")
+
+				if text == "" {
+					fen.UpdatePanes(false)
+					return
+				}
+
+				matcher, err := NewSearchMatcher(text, useRegex, fen.config.SearchCase)
+				if err != nil {
+					inputField.SetTitle(" Invalid regex ")
+					fen.UpdatePanes(false)
+					return
+				}
+
+				fen.searchMatcher = matcher
+				fen.GoSearchMatch(true, true)
+				inputField.SetTitle(" " + strconv.Itoa(fen.SearchMatchCount()) + " matches ")
+				fen.UpdatePanes(false)
+			}
+			inputField.SetChangedFunc(updateSearch)
+
+			inputHistory := fen.InputHistoryFor(INPUT_HISTORY_SEARCH)
+			inputField.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+				if inputHistory.HandleKey(event, inputField, true) {
+					return nil
+				}
+
+				if event.Key() == tcell.KeyCtrlT {
+					useRegex = !useRegex
+					inputField.SetLabel(searchLabel())
+					updateSearch(inputField.GetText())
+					return nil
+				}
+				return event
+			})
+
 			inputField.SetDoneFunc(func(key tcell.Key) {
 				pages.RemovePage("popup")
 
 				if key == tcell.KeyEscape {
+					fen.sel = selBeforeSearch
+					fen.searchMatcher = searchMatcherBeforeSearch
+					fen.UpdatePanes(false)
 					return
 				}
 
-				err := fen.GoSearchFirstMatch(inputField.GetText())
-				if err != nil {
+				// An empty search clears the highlighted matches
+				if inputField.GetText() == "" {
+					return
+				}
+
+				fen.AddToInputHistory(INPUT_HISTORY_SEARCH, inputField.GetText())
+				if fen.searchMatcher == nil || !fen.searchMatcher.Match(fen.middlePane.EntryNameFromPath(fen.sel)) {
 					// FIXME: We need a log window or something
 					fen.bottomBar.TemporarilyShowTextInstead("Nothing found")
 				} else {
@@ -201,6 +278,99 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 			inputField.SetLabelColor(tcell.NewRGBColor(0, 255, 0)) // Green
 			inputField.SetPlaceholderStyle(tcell.StyleDefault.Background(tcell.ColorGray).Dim(true))
 
+			pages.AddPage("popup", centered(inputField, 3), true, true)
+			return nil
+		} else if event.Rune() == '.' || event.Rune() == ',' {
+			err := fen.GoSearchMatch(event.Rune() == '.', false)
+			if err != nil {
+				fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+				return nil
+			}
+
+			// Same code as the wasMovementKey check
+			fen.history.AddToHistory(fen.sel)
+			fen.UpdatePanes(false)
+			return nil
+		} else if event.Rune() == '|' {
+			filterModes := []string{FILTER_SUBSTRING, FILTER_GLOB, FILTER_REGEX}
+			filterMode := FILTER_SUBSTRING
+			filterLabel := func() string {
+				switch filterMode {
+				case FILTER_GLOB:
+					return " Glob filter: "
+				case FILTER_REGEX:
+					return " Regex filter: "
+				}
+				return " Filter: "
+			}
+
+			// The folder to filter, in case it changes while the popup is open
+			folder := fen.wd
+
+			inputField := tview.NewInputField().
+				SetPlaceholder("case-" + fen.config.SearchCase + ", Ctrl+T to switch between substring, glob and regex, empty to clear").
+				SetFieldWidth(-1) // Special feature of my tview fork, github.com/kivattt/tview
+
+			// Edit the current filter
+			filter, hasFilter := fen.filters[folder]
+			if hasFilter {
+				filterMode = filter.filterMode()
+				inputField.SetText(filter.term)
+			}
+			inputField.SetLabel(filterLabel())
+
+			inputHistory := fen.InputHistoryFor(INPUT_HISTORY_FILTER)
+			inputField.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+				if inputHistory.HandleKey(event, inputField, true) {
+					return nil
+				}
+
+				if event.Key() == tcell.KeyCtrlT {
+					filterMode = filterModes[(slices.Index(filterModes, filterMode)+1)%len(filterModes)]
+					inputField.SetLabel(filterLabel())
+					return nil
+				}
+				return event
+			})
+
+			inputField.SetDoneFunc(func(key tcell.Key) {
+				if key == tcell.KeyEscape {
+					pages.RemovePage("popup")
+					return
+				}
+
+				text := inputField.GetText()
+				if text == "" {
+					pages.RemovePage("popup")
+					fen.SetFilter(folder, nil)
+					fen.UpdatePanes(true) // The entries that were filtered out have to be read again
+					fen.bottomBar.TemporarilyShowTextInstead("Filter cleared")
+					return
+				}
+
+				newFilter, err := NewFilterSearchMatcher(text, filterMode, fen.config.SearchCase)
+
+				if err != nil {
+					inputField.SetTitle(" Invalid filter ")
+					return
+				}
+
+				pages.RemovePage("popup")
+				fen.AddToInputHistory(INPUT_HISTORY_FILTER, text)
+				fen.SetFilter(folder, newFilter)
+				fen.UpdatePanes(true) // The previous filter might have filtered out entries matching the new filter
+				fen.history.AddToHistory(fen.sel)
+			})
+
+			inputField.SetBorder(true)
+			inputField.SetBorderStyle(tcell.StyleDefault.Background(tcell.ColorBlack))
+			inputField.SetTitleColor(tcell.ColorDefault)
+			inputField.SetFieldBackgroundColor(tcell.ColorGray)
+			inputField.SetFieldTextColor(tcell.ColorBlack)
+			inputField.SetLabelStyle(tcell.StyleDefault.Background(tcell.ColorBlack))
+			inputField.SetLabelColor(tcell.NewRGBColor(0, 255, 0)) // Green
+			inputField.SetPlaceholderStyle(tcell.StyleDefault.Background(tcell.ColorGray).Dim(true))
+
 			pages.AddPage("popup", centered(inputField, 3), true, true)
 			return nil
 		} else if event.Rune() == 'A' {
@@ -263,7 +433,7 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 						// We can't use fen.GoPath() here because it would enter directories
 						fen.UpdatePanes(true)
 						fen.sel = newPath
-						fen.middlePane.SetSelectedEntryFromString(filepath.Base(fen.sel)) // fen.UpdatePanes() overwrites fen.sel, so we have to set the index
+						fen.middlePane.SetSelectedEntryFromString(fen.middlePane.EntryNameFromPath(fen.sel)) // fen.UpdatePanes() overwrites fen.sel, so we have to set the index
 						fen.history.AddToHistory(newPath)
 					} else {
 						fen.bottomBar.TemporarilyShowTextInstead("Can't rename in no-write mode")
@@ -382,17 +552,118 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 			fen.bottomBar.TemporarilyShowTextInstead("Cut!")
 			return nil
 		} else if event.Rune() == 'z' || event.Key() == tcell.KeyBackspace {
-			fen.config.HiddenFiles = !fen.config.HiddenFiles
+			hiddenFiles := fen.ToggleHiddenFiles(fen.wd)
 			fen.InvalidateFolderFileCountCache()
 			fen.DisableSelectingWithV() // FIXME: We shouldn't disable it, but fixing it to not be buggy would be annoying
 			fen.UpdatePanes(true)
 			fen.history.AddToHistory(fen.sel)
-			if fen.config.HiddenFiles {
+			if hiddenFiles {
 				fen.bottomBar.TemporarilyShowTextInstead("Hidden files: visible")
 			} else {
 				fen.bottomBar.TemporarilyShowTextInstead("Hidden files: hidden")
 			}
 			return nil
+		} else if event.Rune() == 's' || event.Rune() == 'S' {
+			if event.Rune() == 's' {
+				sortBy := fen.CycleSortBy(fen.wd)
+				fen.bottomBar.TemporarilyShowTextInstead("Sort by: " + sortBy)
+			} else {
+				if fen.ToggleSortReverse(fen.wd) {
+					fen.bottomBar.TemporarilyShowTextInstead("Sort reverse: on")
+				} else {
+					fen.bottomBar.TemporarilyShowTextInstead("Sort reverse: off")
+				}
+			}
+
+			fen.DisableSelectingWithV() // The selection indices no longer match up
+			fen.UpdatePanes(true)
+			return nil
+		} else if event.Key() == tcell.KeyCtrlT || event.Key() == tcell.KeyCtrlW || event.Key() == tcell.KeyTab || event.Key() == tcell.KeyBacktab {
+			switch event.Key() {
+			case tcell.KeyCtrlT:
+				fen.NewTab()
+			case tcell.KeyCtrlW:
+				err := fen.CloseTab()
+				if err != nil {
+					fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+					return nil
+				}
+			case tcell.KeyTab, tcell.KeyBacktab:
+				// In dual-pane mode, Tab switches between the panels like in Midnight Commander
+				if fen.dualPane {
+					fen.SwitchPanel()
+					fen.UpdatePanes(false)
+					return nil
+				}
+
+				if event.Key() == tcell.KeyTab {
+					fen.SwitchTab(fen.currentTab + 1)
+				} else {
+					fen.SwitchTab(fen.currentTab - 1)
+				}
+			}
+
+			fen.UpdatePanes(false)
+			fen.bottomBar.TemporarilyShowTextInstead(fen.TabText())
+			return nil
+		} else if event.Rune() == '<' || event.Rune() == '>' {
+			if event.Rune() == '>' {
+				fen.ResizeMiddlePane(1)
+			} else {
+				fen.ResizeMiddlePane(-1)
+			}
+			return nil
+		} else if event.Rune() == 'w' {
+			fen.ToggleMiddlePaneMaximized()
+			return nil
+		} else if event.Rune() == 't' {
+			fen.ToggleDualPane()
+			fen.UpdatePanes(false)
+			if fen.dualPane {
+				fen.bottomBar.TemporarilyShowTextInstead("Dual-pane mode: enabled")
+			} else {
+				fen.bottomBar.TemporarilyShowTextInstead("Dual-pane mode: disabled")
+			}
+			return nil
+		} else if event.Rune() == 'C' || event.Rune() == 'm' {
+			folder, err := fen.OtherPanelFolder()
+			if err != nil {
+				fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+				return nil
+			}
+
+			if fen.config.NoWrite {
+				fen.bottomBar.TemporarilyShowTextInstead("Can't copy or move in no-write mode")
+				return nil
+			}
+
+			paths := fen.selected
+			if len(paths) <= 0 {
+				paths = map[string]bool{fen.sel: true}
+			}
+
+			if event.Rune() == 'C' {
+				fen.PasteInto(folder, paths, "copy")
+				fen.bottomBar.TemporarilyShowTextInstead("Copied to the other panel!")
+			} else {
+				fen.PasteInto(folder, paths, "cut")
+				fen.bottomBar.TemporarilyShowTextInstead("Moved to the other panel!")
+			}
+
+			fen.selected = make(map[string]bool)
+			fen.DisableSelectingWithV()
+			fen.UpdatePanes(false)
+			return nil
+		} else if event.Rune() == 'F' {
+			fen.ToggleFlattened()
+			fen.UpdatePanes(false)
+			fen.history.AddToHistory(fen.sel)
+			if fen.flattened {
+				fen.bottomBar.TemporarilyShowTextInstead("File list mode: enabled")
+			} else {
+				fen.bottomBar.TemporarilyShowTextInstead("File list mode: disabled")
+			}
+			return nil
 		} else if event.Rune() == 'p' {
 			if len(fen.yankSelected) <= 0 {
 				fen.bottomBar.TemporarilyShowTextInstead("Nothing to paste...") // TODO: We need a log we can scroll through
@@ -404,25 +675,7 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 				return nil // TODO: Need a msg showing nothing was done in a log (we can scroll through)
 			}
 
-			if fen.yankType == "copy" {
-				for e := range fen.yankSelected {
-					newPath := FilePathUniqueNameIfAlreadyExists(filepath.Join(fen.wd, filepath.Base(e)))
-					go fen.fileOperationsHandler.QueueOperation(FileOperation{operation: Copy, path: e, newPath: newPath})
-				}
-			} else if fen.yankType == "cut" {
-				for e := range fen.yankSelected {
-					newPath := FilePathUniqueNameIfAlreadyExists(filepath.Join(fen.wd, filepath.Base(e)))
-
-					// If we're cutting, then pasting the file to the same location, don't actually do anything
-					if e == filepath.Join(fen.wd, filepath.Base(e)) {
-						continue
-					}
-
-					go fen.fileOperationsHandler.QueueOperation(FileOperation{operation: Rename, path: e, newPath: newPath})
-				}
-			} else {
-				panic("yankType was not \"copy\" or \"cut\"")
-			}
+			fen.PasteInto(fen.wd, fen.yankSelected, fen.yankType)
 
 			// Reset selection after paste
 			fen.yankSelected = make(map[string]bool)
@@ -440,6 +693,7 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 			fen.UpdatePanes(false)
 			return nil
 		} else if event.Key() == tcell.KeyEscape {
+			fen.searchMatcher = nil // Stop highlighting the search matches
 			fen.DisableSelectingWithV()
 			fen.UpdatePanes(false)
 			return nil
@@ -463,24 +717,180 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 				fen.ShowFilepanes()
 			}
 			return nil
-		} else if event.Key() == tcell.KeyDelete || event.Rune() == 'x' {
-			modal := tview.NewModal()
+		} else if event.Key() == tcell.KeyF3 {
+			gitStatusCacheScreen := NewGitStatusCacheScreen(fen)
+			gitStatusCacheScreen.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+				if event.Rune() == 'q' || event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyF3 {
+					pages.RemovePage("popup")
+					fen.ShowFilepanes()
+				} else if event.Key() == tcell.KeyUp || event.Rune() == 'k' {
+					gitStatusCacheScreen.Scroll(-1)
+				} else if event.Key() == tcell.KeyDown || event.Rune() == 'j' {
+					gitStatusCacheScreen.Scroll(1)
+				}
+				return nil
+			})
 
-			modal.SetInputCapture(func(e *tcell.EventKey) *tcell.EventKey {
-				switch e.Rune() {
-				case 'h':
-					return tcell.NewEventKey(tcell.KeyLeft, e.Rune(), e.Modifiers())
-				case 'l':
-					return tcell.NewEventKey(tcell.KeyRight, e.Rune(), e.Modifiers())
-				case 'j':
-					return tcell.NewEventKey(tcell.KeyDown, e.Rune(), e.Modifiers())
-				case 'k':
-					return tcell.NewEventKey(tcell.KeyUp, e.Rune(), e.Modifiers())
+			pages.AddPage("popup", gitStatusCacheScreen, true, true)
+			fen.HideFilepanes()
+			return nil
+		} else if event.Rune() == '+' || event.Rune() == '-' {
+			var text string
+			var err error
+			if event.Rune() == '+' {
+				text, err = fen.GitStage(fen.selectedOrSel())
+			} else {
+				text, err = fen.GitUnstage(fen.selectedOrSel())
+			}
+
+			if err != nil {
+				fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+			} else {
+				fen.bottomBar.TemporarilyShowTextInstead(text)
+			}
+			return nil
+		} else if event.Rune() == 'R' {
+			paths := fen.selectedOrSel()
+
+			var text string
+			if len(paths) == 1 {
+				fileInfo, _ := os.Lstat(paths[0])
+				styleStr := StyleToStyleTagString(FileColor(fileInfo, paths[0]))
+				text = "[red::d]Discard the unstaged changes of[-:-:-:-] " + styleStr + FilenameInvisibleCharactersAsCodeHighlighted(tview.Escape(filepath.Base(paths[0])), styleStr) + "[-:-:-:-] ?"
+			} else {
+				text = "[red::d]Discard the unstaged changes of[-:-:-:-] " + strconv.Itoa(len(paths)) + " selected files ?"
+			}
+
+			showConfirmationModal(app, pages, "popup", text, func(confirmed bool) {
+				if !confirmed {
+					return
 				}
 
-				return e
+				text, err := fen.GitRestore(paths)
+				if err != nil {
+					fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+				} else {
+					fen.bottomBar.TemporarilyShowTextInstead(text)
+				}
+				fen.UpdatePanes(false)
 			})
+			return nil
+		} else if event.Rune() == '=' {
+			fen.gitPreview = (fen.gitPreview + 1) % (GIT_PREVIEW_BLAME + 1)
+			switch fen.gitPreview {
+			case GIT_PREVIEW_DIFF:
+				fen.bottomBar.TemporarilyShowTextInstead("Showing the git diff of changed files in the right pane")
+			case GIT_PREVIEW_LOG:
+				fen.bottomBar.TemporarilyShowTextInstead("Showing the git log of files in the right pane")
+			case GIT_PREVIEW_BLAME:
+				fen.bottomBar.TemporarilyShowTextInstead("Showing the git blame of files in the right pane")
+			default:
+				fen.bottomBar.TemporarilyShowTextInstead("Previewing files in the right pane")
+			}
+			return nil
+		} else if event.Rune() == ']' || event.Rune() == '[' {
+			if !fen.config.GitStatus {
+				fen.bottomBar.TemporarilyShowTextInstead("Enable fen.git_status in the config to go to changed files")
+				return nil
+			}
+
+			text, err := fen.GoToChangedFile(event.Rune() == ']')
+			if err != nil {
+				fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+			} else {
+				fen.history.AddToHistory(fen.sel)
+				fen.bottomBar.TemporarilyShowTextInstead(text)
+			}
+			return nil
+		} else if event.Rune() == '*' {
+			if !fen.config.GitStatus {
+				fen.bottomBar.TemporarilyShowTextInstead("Enable fen.git_status in the config to show changed files only")
+				return nil
+			}
+
+			fen.ToggleChangedFilesOnly()
+			fen.history.AddToHistory(fen.sel)
+			if fen.changedFilesOnly {
+				fen.bottomBar.TemporarilyShowTextInstead("Showing changed files only")
+			} else {
+				fen.bottomBar.TemporarilyShowTextInstead("Showing all files")
+			}
+			return nil
+		} else if event.Rune() == 'u' {
+			diskUsageScreen := NewDiskUsageScreen(fen, fen.wd)
+			closeDiskUsageScreen := func() {
+				diskUsageScreen.Cancel()
+				pages.RemovePage("popup")
+				fen.ShowFilepanes()
+			}
+
+			diskUsageScreen.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+				if event.Rune() == 'q' || event.Key() == tcell.KeyEscape {
+					closeDiskUsageScreen()
+				} else if event.Key() == tcell.KeyUp || event.Rune() == 'k' {
+					diskUsageScreen.MoveSelection(-1)
+				} else if event.Key() == tcell.KeyDown || event.Rune() == 'j' {
+					diskUsageScreen.MoveSelection(1)
+				} else if event.Key() == tcell.KeyPgUp {
+					diskUsageScreen.MoveSelection(-10)
+				} else if event.Key() == tcell.KeyPgDn {
+					diskUsageScreen.MoveSelection(10)
+				} else if event.Key() == tcell.KeyHome || event.Rune() == 'g' {
+					diskUsageScreen.GoTopOrBottom(true)
+				} else if event.Key() == tcell.KeyEnd || event.Rune() == 'G' {
+					diskUsageScreen.GoTopOrBottom(false)
+				} else if event.Key() == tcell.KeyLeft || event.Rune() == 'h' || event.Key() == tcell.KeyBackspace || event.Key() == tcell.KeyBackspace2 {
+					diskUsageScreen.GoLeft()
+				} else if event.Key() == tcell.KeyRight || event.Rune() == 'l' || event.Key() == tcell.KeyEnter {
+					// Enter on a file goes to it
+					if !diskUsageScreen.GoRight() && event.Key() == tcell.KeyEnter {
+						path, ok := diskUsageScreen.SelectedPath()
+						if !ok {
+							return nil
+						}
+
+						closeDiskUsageScreen()
+						_, err := fen.GoPath(path)
+						if err != nil {
+							fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+						}
+					}
+				} else if event.Rune() == 'a' {
+					diskUsageScreen.ToggleApparentSize()
+				} else if event.Rune() == 'r' || event.Key() == tcell.KeyF5 {
+					diskUsageScreen.Scan(diskUsageScreen.RootPath())
+				} else if event.Key() == tcell.KeyDelete || event.Rune() == 'x' {
+					path, ok := diskUsageScreen.SelectedPath()
+					if !ok {
+						return nil
+					}
+
+					if fen.config.NoWrite {
+						fen.bottomBar.TemporarilyShowTextInstead("Can't delete in no-write mode")
+						return nil
+					}
+
+					pathInfo, _ := os.Lstat(path)
+					styleStr := StyleToStyleTagString(FileColor(pathInfo, path))
+					text := "[red::d]Delete[-:-:-:-] " + styleStr + FilenameInvisibleCharactersAsCodeHighlighted(tview.Escape(filepath.Base(path)), styleStr) + "[-:-:-:-] ?"
+					showConfirmationModal(app, pages, "diskUsageDelete", text, func(confirmed bool) {
+						app.SetFocus(diskUsageScreen)
+						if !confirmed {
+							return
+						}
 
+						diskUsageScreen.RemoveSelected()
+						go fen.fileOperationsHandler.QueueOperation(FileOperation{operation: Delete, path: path})
+					})
+				}
+				return nil
+			})
+
+			pages.AddPage("popup", diskUsageScreen, true, true)
+			fen.HideFilepanes()
+			return nil
+		} else if event.Key() == tcell.KeyDelete || event.Rune() == 'x' {
+			var text string
 			fileToDelete := ""
 
 			if len(fen.selected) <= 0 {
@@ -488,7 +898,7 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 				fileToDeleteInfo, _ := os.Lstat(fileToDelete)
 				// When the text wraps, color styling gets reset on line breaks. I have not found a good solution yet
 				styleStr := StyleToStyleTagString(FileColor(fileToDeleteInfo, fileToDelete))
-				modal.SetText("[red::d]Delete[-:-:-:-] " + styleStr + FilenameInvisibleCharactersAsCodeHighlighted(tview.Escape(filepath.Base(fileToDelete)), styleStr) + "[-:-:-:-] ?")
+				text = "[red::d]Delete[-:-:-:-] " + styleStr + FilenameInvisibleCharactersAsCodeHighlighted(tview.Escape(filepath.Base(fileToDelete)), styleStr) + "[-:-:-:-] ?"
 			} else {
 				selectedFromMultipleFolders := false
 
@@ -506,51 +916,35 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 				}
 
 				if selectedFromMultipleFolders {
-					modal.SetText("[red::d]Delete[-:-:-:-] " + tview.Escape(strconv.Itoa(len(fen.selected))) + " selected files [:red]from multiple folders[-:-:-:-] ?")
+					text = "[red::d]Delete[-:-:-:-] " + tview.Escape(strconv.Itoa(len(fen.selected))) + " selected files [:red]from multiple folders[-:-:-:-] ?"
 				} else {
-					modal.SetText("[red::d]Delete[-:-:-:-] " + tview.Escape(strconv.Itoa(len(fen.selected))) + " selected files ?")
+					text = "[red::d]Delete[-:-:-:-] " + tview.Escape(strconv.Itoa(len(fen.selected))) + " selected files ?"
 				}
 			}
 
-			modal.
-				AddButtons([]string{"Yes", "No"}).
-				SetFocus(1). // Default is "No"
-				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-					pages.RemovePage("popup")
-
-					if buttonIndex != 0 {
-						return
-					}
+			showConfirmationModal(app, pages, "popup", text, func(confirmed bool) {
+				if !confirmed {
+					return
+				}
 
-					if fen.config.NoWrite {
-						fen.bottomBar.TemporarilyShowTextInstead("Can't delete in no-write mode")
-						return
-					}
+				if fen.config.NoWrite {
+					fen.bottomBar.TemporarilyShowTextInstead("Can't delete in no-write mode")
+					return
+				}
 
-					if len(fen.selected) <= 0 {
-						go fen.fileOperationsHandler.QueueOperation(FileOperation{operation: Delete, path: fileToDelete})
-					} else {
-						for filePath := range fen.selected {
-							go fen.fileOperationsHandler.QueueOperation(FileOperation{operation: Delete, path: filePath})
-						}
+				if len(fen.selected) <= 0 {
+					go fen.fileOperationsHandler.QueueOperation(FileOperation{operation: Delete, path: fileToDelete})
+				} else {
+					for filePath := range fen.selected {
+						go fen.fileOperationsHandler.QueueOperation(FileOperation{operation: Delete, path: filePath})
 					}
+				}
 
-					fen.selected = make(map[string]bool)
-
-					fen.DisableSelectingWithV()
-					fen.UpdatePanes(false)
-				})
-
-			modal.SetBorder(true)
-
-			modal.Box.SetBackgroundColor(tcell.ColorBlack) // This sets the border background color
-			modal.SetBackgroundColor(tcell.ColorBlack)
-
-			modal.SetButtonBackgroundColor(tcell.ColorDefault)
-			modal.SetButtonTextColor(tcell.ColorRed)
+				fen.selected = make(map[string]bool)
 
-			pages.AddPage("popup", modal, true, true)
-			app.SetFocus(modal)
+				fen.DisableSelectingWithV()
+				fen.UpdatePanes(false)
+			})
 			return nil
 		} else if event.Rune() == 'c' {
 			inputField := tview.NewInputField().
@@ -568,6 +962,7 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 						return
 					}
 
+					fen.AddToInputHistory(INPUT_HISTORY_GOTO_PATH, inputField.GetText())
 					path, pathErr := fen.GoPath(ExpandTilde(inputField.GetText()))
 					if pathErr != nil {
 						pages.RemovePage("popup")
@@ -607,7 +1002,7 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 				var ret []string
 				for _, e := range dir {
 					if e.IsDir() {
-						if !fen.config.HiddenFiles && strings.HasPrefix(e.Name(), ".") {
+						if !fen.HiddenFilesIn(pathToUse) && strings.HasPrefix(e.Name(), ".") {
 							continue
 						}
 						ret = append(ret, filepath.Join(pathToUse, e.Name())+string(os.PathSeparator))
@@ -615,7 +1010,13 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 				}
 				return ret
 			})
+			inputHistory := fen.InputHistoryFor(INPUT_HISTORY_GOTO_PATH)
 			inputField.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+				// The Up / Down arrow keys are used to select autocomplete entries after pressing Tab
+				if inputHistory.HandleKey(event, inputField, !enterWillSelectAutoCompleteInGotoPath) {
+					return nil
+				}
+
 				if event.Key() == tcell.KeyTab {
 					enterWillSelectAutoCompleteInGotoPath = true
 					return tcell.NewEventKey(tcell.KeyDown, 'j', tcell.ModNone)
@@ -644,8 +1045,191 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 			pages.AddPage("popup", centered(inputField, 3), true, true)
 			app.SetFocus(inputField)
 			return nil
+		} else if event.Rune() == 'J' {
+			inputField := tview.NewInputField().
+				SetLabel(" Jump to: ").
+				SetPlaceholder("Parts of a visited folder path, ranked by how often and recently you visited it").
+				SetFieldWidth(-1) // Special feature of my tview fork, github.com/kivattt/tview
+
+			var candidates []string
+			jump := func(path string) {
+				pages.RemovePage("popup")
+				_, err := fen.GoPath(path)
+				if err != nil {
+					fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+					return
+				}
+				fen.history.AddToHistory(fen.sel)
+			}
+
+			inputField.SetAutocompleteFunc(func(currentText string) []string {
+				candidates = fen.JumpCandidates(currentText, 20)
+
+				entries := make([]string, len(candidates))
+				for i, candidate := range candidates {
+					entries[i] = tview.Escape(candidate)
+				}
+				return entries
+			})
+
+			// Going to the entry by its index, since the text of the entry is escaped
+			inputField.SetAutocompletedFunc(func(text string, index int, source int) bool {
+				if source == tview.AutocompletedNavigate {
+					return false
+				}
+
+				if index >= 0 && index < len(candidates) {
+					jump(candidates[index])
+				}
+				return true
+			})
+
+			inputField.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+				// The autocomplete list would otherwise take the first Escape
+				if event.Key() == tcell.KeyEscape {
+					pages.RemovePage("popup")
+					return nil
+				}
+				return event
+			})
+
+			inputField.SetDoneFunc(func(key tcell.Key) {
+				if key == tcell.KeyEnter && len(candidates) > 0 {
+					jump(candidates[0])
+					return
+				}
+
+				pages.RemovePage("popup")
+				if key == tcell.KeyEnter {
+					fen.bottomBar.TemporarilyShowTextInstead("No visited folders matching \"" + inputField.GetText() + "\"")
+				}
+			})
+
+			inputField.SetAutocompleteStyles(tcell.ColorBlack, tcell.StyleDefault.Foreground(tcell.ColorBlue).Bold(true).Background(tcell.ColorBlack), tcell.StyleDefault.Foreground(tcell.ColorBlue).Bold(true).Background(tcell.ColorWhite))
+
+			inputField.SetTitleColor(tcell.ColorDefault)
+			inputField.SetFieldBackgroundColor(tcell.ColorGray)
+			inputField.SetFieldTextColor(tcell.ColorBlack)
+			inputField.SetBackgroundColor(tcell.ColorBlack)
+			inputField.SetLabelStyle(tcell.StyleDefault.Background(tcell.ColorBlack)) // This has to be before the .SetLabelColor
+			inputField.SetLabelColor(tcell.NewRGBColor(0, 255, 0))                    // Green
+			inputField.SetPlaceholderStyle(tcell.StyleDefault.Background(tcell.ColorGray).Dim(true))
+			inputField.SetBorder(true)
+			inputField.SetBorderStyle(tcell.StyleDefault.Background(tcell.ColorBlack))
+
+			pages.AddPage("popup", centered(inputField, 3), true, true)
+			app.SetFocus(inputField)
+			return nil
+		} else if event.Rune() == 'B' {
+			bookmarksScreen := NewBookmarksScreen(fen)
+			closeBookmarksScreen := func() {
+				pages.RemovePage("popup")
+				fen.ShowFilepanes()
+			}
+
+			// Asks for the name of a bookmark, done is only called if Enter was pressed
+			promptBookmarkName := func(label, text string, done func(name string)) {
+				inputField := tview.NewInputField().
+					SetLabel(label).
+					SetText(text).
+					SetFieldWidth(-1) // Special feature of my tview fork, github.com/kivattt/tview
+
+				inputField.SetDoneFunc(func(key tcell.Key) {
+					pages.RemovePage("bookmarksInput")
+					app.SetFocus(bookmarksScreen)
+					if key == tcell.KeyEnter {
+						done(inputField.GetText())
+					}
+				})
+
+				inputField.SetTitleColor(tcell.ColorDefault)
+				inputField.SetFieldBackgroundColor(tcell.ColorGray)
+				inputField.SetFieldTextColor(tcell.ColorBlack)
+				inputField.SetBackgroundColor(tcell.ColorBlack)
+				inputField.SetLabelStyle(tcell.StyleDefault.Background(tcell.ColorBlack)) // This has to be before the .SetLabelColor
+				inputField.SetLabelColor(tcell.NewRGBColor(0, 255, 0))                    // Green
+				inputField.SetBorder(true)
+				inputField.SetBorderStyle(tcell.StyleDefault.Background(tcell.ColorBlack))
+
+				pages.AddPage("bookmarksInput", centered(inputField, 3), true, true)
+				app.SetFocus(inputField)
+			}
+
+			bookmarksScreen.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+				if event.Rune() == 'q' || event.Key() == tcell.KeyEscape {
+					closeBookmarksScreen()
+				} else if event.Key() == tcell.KeyUp || event.Rune() == 'k' {
+					bookmarksScreen.MoveSelection(-1)
+				} else if event.Key() == tcell.KeyDown || event.Rune() == 'j' {
+					bookmarksScreen.MoveSelection(1)
+				} else if event.Key() == tcell.KeyPgUp {
+					bookmarksScreen.MoveSelection(-10)
+				} else if event.Key() == tcell.KeyPgDn {
+					bookmarksScreen.MoveSelection(10)
+				} else if event.Key() == tcell.KeyHome || event.Rune() == 'g' {
+					bookmarksScreen.GoTopOrBottom(true)
+				} else if event.Key() == tcell.KeyEnd || event.Rune() == 'G' {
+					bookmarksScreen.GoTopOrBottom(false)
+				} else if event.Key() == tcell.KeyRight || event.Rune() == 'l' || event.Key() == tcell.KeyEnter {
+					bookmark, ok := bookmarksScreen.Selected()
+					if !ok {
+						return nil
+					}
+
+					closeBookmarksScreen()
+					path, err := fen.GoNamedBookmark(bookmark.Name)
+					if err != nil {
+						fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+						return nil
+					}
+					fen.history.AddToHistory(fen.sel)
+					fen.bottomBar.TemporarilyShowTextInstead("Moved to bookmark \"" + bookmark.Name + "\": \"" + path + "\"")
+				} else if event.Rune() == 'a' {
+					folder := fen.wd
+					promptBookmarkName(" Bookmark "+folder+" as: ", filepath.Base(folder), func(name string) {
+						err := fen.AddNamedBookmark(name, folder)
+						if err != nil {
+							fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+							return
+						}
+						bookmarksScreen.SelectName(strings.TrimSpace(name))
+					})
+				} else if event.Rune() == 'r' {
+					bookmark, ok := bookmarksScreen.Selected()
+					if !ok {
+						return nil
+					}
+
+					promptBookmarkName(" Rename bookmark: ", bookmark.Name, func(name string) {
+						err := fen.RenameNamedBookmark(bookmark.Name, name)
+						if err != nil {
+							fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+							return
+						}
+						bookmarksScreen.SelectName(strings.TrimSpace(name))
+					})
+				} else if event.Key() == tcell.KeyDelete || event.Rune() == 'x' {
+					bookmark, ok := bookmarksScreen.Selected()
+					if !ok {
+						return nil
+					}
+
+					err := fen.RemoveNamedBookmark(bookmark.Name)
+					if err != nil {
+						fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+					} else {
+						fen.bottomBar.TemporarilyShowTextInstead("Removed bookmark \"" + bookmark.Name + "\"")
+					}
+				}
+				return nil
+			})
+
+			pages.AddPage("popup", bookmarksScreen, true, true)
+			fen.HideFilepanes()
+			return nil
 		} else if event.Key() == tcell.KeyF5 {
 			fen.InvalidateFolderFileCountCache()
+			fen.folderSizeHandler.InvalidateAll()
 			fen.UpdatePanes(true)
 			app.Sync()
 			fen.TriggerGitStatus()
@@ -691,9 +1275,7 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 			}
 
 			repositoryPath, err := fen.gitStatusHandler.TryFindParentGitRepository(filepath.Dir(fen.sel))
-
-			stat, statErr := os.Lstat(filepath.Join(filepath.Dir(fen.sel), ".git"))
-			atRootOfRepository := statErr == nil && stat.IsDir() && (filepath.Dir(fen.sel) == repositoryPath)
+			atRootOfRepository := filepath.Dir(fen.sel) == repositoryPath
 
 			if err != nil || atRootOfRepository {
 				fen.GoRootPath()
@@ -774,20 +1356,28 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 			inputField.SetDoneFunc(func(key tcell.Key) {
 				if key == tcell.KeyEscape {
 					searchFilenames.mutex.Lock()
-					searchFilenames.cancel = true
+					searchFilenames.Cancel()
 					searchFilenames.mutex.Unlock()
 					pages.RemovePage("popup")
 					return
 				}
 			})
 
+			inputHistory := fen.InputHistoryFor(INPUT_HISTORY_FILENAME_SEARCH)
 			inputField.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+				// The Up / Down arrow keys are used to select filenames.
+				// This has to be done before locking the mutex, since changing the text calls the changed func
+				if inputHistory.HandleKey(event, inputField, false) {
+					return nil
+				}
+
 				searchFilenames.mutex.Lock()
 				defer searchFilenames.mutex.Unlock()
 
 				if event.Key() == tcell.KeyEnter {
+					fen.AddToInputHistory(INPUT_HISTORY_FILENAME_SEARCH, inputField.GetText())
 					defer func() {
-						searchFilenames.cancel = true
+						searchFilenames.Cancel()
 						pages.RemovePage("popup")
 					}()
 
@@ -822,6 +1412,9 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 				} else if event.Modifiers()&tcell.ModCtrl != 0 && event.Key() == tcell.KeyEnd {
 					searchFilenames.GoBottom()
 					return nil
+				} else if event.Key() == tcell.KeyCtrlS {
+					searchFilenames.CycleSortBy()
+					return nil
 				}
 
 				// While loading, if you press backspace with an empty search
@@ -859,6 +1452,14 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 			inputField.SetLabelStyle(tcell.StyleDefault.Background(tcell.ColorBlack)) // This has to be before the .SetLabelColor
 			inputField.SetLabelColor(tcell.NewRGBColor(0, 255, 0))                    // Green
 
+			inputHistory := fen.InputHistoryFor(INPUT_HISTORY_SHELL)
+			inputField.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+				if inputHistory.HandleKey(event, inputField, true) {
+					return nil
+				}
+				return event
+			})
+
 			inputField.SetDoneFunc(func(key tcell.Key) {
 				if key == tcell.KeyEscape {
 					pages.RemovePage("popup")
@@ -878,6 +1479,7 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 				}
 
 				command := inputField.GetText()
+				fen.AddToInputHistory(INPUT_HISTORY_SHELL, command)
 				var err error
 				var exitCode int
 				app.Suspend(func() {
@@ -1015,6 +1617,10 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 						optionsForm.AddDropDown(fieldName, ValidFilenameSearchCaseValues[:], slices.Index(ValidFilenameSearchCaseValues[:], fieldValue), func(option string, optionIndex int) {
 							*fieldPtr.(*string) = option
 						})
+					} else if fieldName == "search_case" {
+						optionsForm.AddDropDown(fieldName, ValidSearchCaseValues[:], slices.Index(ValidSearchCaseValues[:], fieldValue), func(option string, optionIndex int) {
+							*fieldPtr.(*string) = option
+						})
 					} else {
 						panic("Unknown string option \"" + fieldName + "\"")
 					}
@@ -1068,6 +1674,47 @@ func setAppInputHandler(app *tview.Application, pages *tview.Pages, fen *Fen, li
 	})
 }
 
+// Shows a "Yes" / "No" modal like the delete confirmation on the page called pageName, "No" is selected by default.
+// The page is removed before onClose is called
+func showConfirmationModal(app *tview.Application, pages *tview.Pages, pageName, text string, onClose func(confirmed bool)) {
+	modal := tview.NewModal()
+
+	modal.SetInputCapture(func(e *tcell.EventKey) *tcell.EventKey {
+		switch e.Rune() {
+		case 'h':
+			return tcell.NewEventKey(tcell.KeyLeft, e.Rune(), e.Modifiers())
+		case 'l':
+			return tcell.NewEventKey(tcell.KeyRight, e.Rune(), e.Modifiers())
+		case 'j':
+			return tcell.NewEventKey(tcell.KeyDown, e.Rune(), e.Modifiers())
+		case 'k':
+			return tcell.NewEventKey(tcell.KeyUp, e.Rune(), e.Modifiers())
+		}
+
+		return e
+	})
+
+	modal.SetText(text)
+	modal.
+		AddButtons([]string{"Yes", "No"}).
+		SetFocus(1). // Default is "No"
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			pages.RemovePage(pageName)
+			onClose(buttonIndex == 0)
+		})
+
+	modal.SetBorder(true)
+
+	modal.Box.SetBackgroundColor(tcell.ColorBlack) // This sets the border background color
+	modal.SetBackgroundColor(tcell.ColorBlack)
+
+	modal.SetButtonBackgroundColor(tcell.ColorDefault)
+	modal.SetButtonTextColor(tcell.ColorRed)
+
+	pages.AddPage(pageName, modal, true, true)
+	app.SetFocus(modal)
+}
+
 func setAppMouseHandler(app *tview.Application, pages *tview.Pages, fen *Fen) {
 	lastWheelUpTime := time.Now()
 	lastWheelDownTime := time.Now()
@@ -1150,6 +1797,12 @@ func setAppMouseHandler(app *tview.Application, pages *tview.Pages, fen *Fen) {
 				break
 			}
 
+			// Clicking the inactive panel in dual-pane mode makes it the active one
+			if fen.dualPane && fen.otherPane.InRect(mouseX, mouseY) {
+				fen.SwitchPanel()
+				x, y, w, h = fen.middlePane.GetInnerRect()
+			}
+
 			if mouseX < x {
 				fen.GoLeft()
 			} else if mouseX > x+w {
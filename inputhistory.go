@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// The names of the input popups with a separate history
+const (
+	INPUT_HISTORY_SEARCH          = "search"          // The "/" popup
+	INPUT_HISTORY_FILENAME_SEARCH = "filename_search" // The "f" popup
+	INPUT_HISTORY_GOTO_PATH       = "goto_path"       // The "c" popup
+	INPUT_HISTORY_SHELL           = "shell"           // The "!" popup
+	INPUT_HISTORY_FILTER          = "filter"          // The "|" popup
+)
+
+const inputHistoryMaxEntries = 200
+
+// Browses the previously entered texts of an input popup, like the Up/Down arrow keys and Ctrl+R do in bash
+type InputHistory struct {
+	entries []string // Oldest first
+	index   int      // The index of the entry shown in the input field, len(entries) when showing the text being typed
+	draft   string   // The text being typed before we started browsing
+
+	reverseSearching  bool
+	reverseSearchTerm string
+	label             string // The label of the input field before we started reverse searching
+}
+
+func NewInputHistory(entries []string) *InputHistory {
+	return &InputHistory{
+		entries: entries,
+		index:   len(entries),
+	}
+}
+
+// Returns the older entry to show, current is the text currently in the input field.
+// Returns false if there are no older entries
+func (h *InputHistory) Previous(current string) (string, bool) {
+	if h.index <= 0 {
+		return "", false
+	}
+
+	if h.index == len(h.entries) {
+		h.draft = current
+	}
+
+	h.index--
+	return h.entries[h.index], true
+}
+
+// Returns the newer entry to show, or the text that was being typed before browsing.
+// Returns false if we aren't browsing the history
+func (h *InputHistory) Next() (string, bool) {
+	if h.index >= len(h.entries) {
+		return "", false
+	}
+
+	h.index++
+	if h.index == len(h.entries) {
+		return h.draft, true
+	}
+
+	return h.entries[h.index], true
+}
+
+// Returns the next older entry containing the text that was in the input field when the reverse search started.
+// Returns false if there are no more matches
+func (h *InputHistory) ReverseSearch(current string) (string, bool) {
+	if !h.reverseSearching {
+		h.reverseSearching = true
+		h.reverseSearchTerm = current
+		if h.index == len(h.entries) {
+			h.draft = current
+		}
+	}
+
+	for i := min(h.index, len(h.entries)) - 1; i >= 0; i-- {
+		if strings.Contains(h.entries[i], h.reverseSearchTerm) {
+			h.index = i
+			return h.entries[i], true
+		}
+	}
+
+	return "", false
+}
+
+// Handles the history keys of an input field, returns true if the event was used.
+// Ctrl+P / Ctrl+N go to the previous / next entry, and Ctrl+R searches backwards.
+// If upDown is true, the Up / Down arrow keys do the same as Ctrl+P / Ctrl+N
+func (h *InputHistory) HandleKey(event *tcell.EventKey, inputField *tview.InputField, upDown bool) bool {
+	if event.Key() == tcell.KeyCtrlR {
+		if !h.reverseSearching {
+			h.label = inputField.GetLabel()
+		}
+
+		text, ok := h.ReverseSearch(inputField.GetText())
+		inputField.SetLabel(" (reverse-search) " + strings.TrimLeft(h.label, " "))
+		if ok {
+			inputField.SetText(text)
+		}
+		return true
+	}
+
+	if h.reverseSearching {
+		h.reverseSearching = false
+		inputField.SetLabel(h.label)
+	}
+
+	var text string
+	var ok bool
+	if event.Key() == tcell.KeyCtrlP || (upDown && event.Key() == tcell.KeyUp) {
+		text, ok = h.Previous(inputField.GetText())
+	} else if event.Key() == tcell.KeyCtrlN || (upDown && event.Key() == tcell.KeyDown) {
+		text, ok = h.Next()
+	} else {
+		return false
+	}
+
+	if ok {
+		inputField.SetText(text)
+	}
+	return true
+}
+
+// Returns entries with text moved to, or added at the end, removing the oldest entries above maxEntries
+func AddInputHistoryEntry(entries []string, text string, maxEntries int) []string {
+	entries = slices.DeleteFunc(entries, func(entry string) bool {
+		return entry == text
+	})
+	entries = append(entries, text)
+
+	if len(entries) > maxEntries {
+		entries = entries[len(entries)-maxEntries:]
+	}
+	return entries
+}
+
+func inputHistoryFilePath() (string, error) {
+	cacheDir, err := FenCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cacheDir, "input_history.json"), nil
+}
+
+// Returns a new InputHistory for the popup, name is one of the INPUT_HISTORY_ constants
+func (fen *Fen) InputHistoryFor(name string) *InputHistory {
+	fen.loadInputHistories()
+	return NewInputHistory(slices.Clone(fen.inputHistories[name]))
+}
+
+// Adds text to the history of the popup and saves it to disk, name is one of the INPUT_HISTORY_ constants
+func (fen *Fen) AddToInputHistory(name, text string) {
+	if text == "" {
+		return
+	}
+
+	fen.loadInputHistories()
+	fen.inputHistories[name] = AddInputHistoryEntry(fen.inputHistories[name], text, inputHistoryMaxEntries)
+
+	if fen.config.NoWrite {
+		return
+	}
+
+	path, err := inputHistoryFilePath()
+	if err != nil {
+		return
+	}
+
+	data, err := json.Marshal(fen.inputHistories)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return
+	}
+
+	// The history can contain shell commands, so only the user can read it
+	err = os.WriteFile(path, data, 0o600)
+	if err != nil {
+		fen.bottomBar.TemporarilyShowTextInstead("Failed to save input history: " + err.Error())
+	}
+}
+
+func (fen *Fen) loadInputHistories() {
+	if fen.inputHistories != nil {
+		return
+	}
+
+	fen.inputHistories = make(map[string][]string)
+
+	path, err := inputHistoryFilePath()
+	if err != nil {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	// If the file is corrupt, we just start over with an empty history
+	_ = json.Unmarshal(data, &fen.inputHistories)
+	if fen.inputHistories == nil {
+		fen.inputHistories = make(map[string][]string)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAddInputHistoryEntry(t *testing.T) {
+	var entries []string
+	entries = AddInputHistoryEntry(entries, "a", 3)
+	entries = AddInputHistoryEntry(entries, "b", 3)
+	entries = AddInputHistoryEntry(entries, "a", 3)
+	if !slices.Equal(entries, []string{"b", "a"}) {
+		t.Fatal("Expected duplicates to be moved to the end, but got", entries)
+	}
+
+	entries = AddInputHistoryEntry(entries, "c", 3)
+	entries = AddInputHistoryEntry(entries, "d", 3)
+	if !slices.Equal(entries, []string{"a", "c", "d"}) {
+		t.Fatal("Expected the oldest entries to be removed, but got", entries)
+	}
+}
+
+func TestInputHistory(t *testing.T) {
+	h := NewInputHistory([]string{"ls -la", "make", "ls"})
+
+	text, ok := h.Previous("typed")
+	if !ok || text != "ls" {
+		t.Fatal("Expected \"ls\", but got", text)
+	}
+	text, _ = h.Previous(text)
+	if text != "make" {
+		t.Fatal("Expected \"make\", but got", text)
+	}
+	h.Previous(text)
+	_, ok = h.Previous(text)
+	if ok {
+		t.Fatal("Expected no entries older than the first one")
+	}
+
+	h.Next()
+	h.Next()
+	text, ok = h.Next()
+	if !ok || text != "typed" {
+		t.Fatal("Expected to get back the text being typed, but got", text)
+	}
+	_, ok = h.Next()
+	if ok {
+		t.Fatal("Expected no entries newer than the text being typed")
+	}
+
+	text, ok = h.ReverseSearch("ls")
+	if !ok || text != "ls" {
+		t.Fatal("Expected \"ls\", but got", text)
+	}
+	text, ok = h.ReverseSearch(text)
+	if !ok || text != "ls -la" {
+		t.Fatal("Expected \"ls -la\", but got", text)
+	}
+	_, ok = h.ReverseSearch(text)
+	if ok {
+		t.Fatal("Expected no more matches")
+	}
+}
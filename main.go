@@ -9,6 +9,7 @@ import (
 	"runtime"
 	"runtime/pprof"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/gdamore/tcell/v2"
@@ -77,7 +78,7 @@ func main() {
 	selectPaths := flag.Bool("select", false, "select PATHS")
 
 	configFilename := flag.String("config", defaultConfigFilenamePath, "use configuration file")
-	sortBy := flag.String("sort-by", defaultConfigValues.SortBy, "sort files ("+strings.Join(ValidSortByValues[:], ", ")+")")
+	sortBy := flag.String("sort-by", defaultConfigValues.SortBy, "sort files ("+strings.Join(ValidSortByValues[:], ", ")+"), can be combined with commas")
 	sortReverse := flag.Bool("sort-reverse", defaultConfigValues.SortReverse, "reverse sort")
 	fileSizeFormat := flag.String("file-size-format", defaultConfigValues.FileSizeFormat, "file size format ("+strings.Join(ValidFileSizeFormatValues[:], ", ")+")")
 
@@ -238,10 +239,18 @@ func main() {
 		os.Exit(1)
 	}
 
-	if !slices.Contains(ValidSortByValues[:], fen.config.SortBy) {
-		fmt.Fprintln(os.Stderr, "Invalid sort_by value \""+fen.config.SortBy+"\"")
-		fmt.Fprintln(os.Stderr, "Valid values: "+strings.Join(ValidSortByValues[:], ", "))
-		os.Exit(1)
+	sortByValues := []string{fen.config.SortBy}
+	for _, entry := range fen.config.FolderSortBy {
+		sortByValues = append(sortByValues, entry.SortBy)
+	}
+
+	for _, sortBy := range sortByValues {
+		err := ValidateSortBy(sortBy)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			fmt.Fprintln(os.Stderr, "Valid values: "+strings.Join(ValidSortByValues[:], ", ")+" (can be combined with commas, like \"file-extension,size\")")
+			os.Exit(1)
+		}
 	}
 
 	if !slices.Contains(ValidFilenameSearchCaseValues[:], fen.config.FilenameSearchCase) {
@@ -250,6 +259,32 @@ func main() {
 		os.Exit(1)
 	}
 
+	if !slices.Contains(ValidSearchCaseValues[:], fen.config.SearchCase) {
+		fmt.Fprintln(os.Stderr, "Invalid search_case value \""+fen.config.SearchCase+"\"")
+		fmt.Fprintln(os.Stderr, "Valid values: "+strings.Join(ValidSearchCaseValues[:], ", "))
+		os.Exit(1)
+	}
+
+	for _, column := range fen.config.Columns {
+		if !slices.Contains(ValidColumnValues[:], column) {
+			fmt.Fprintln(os.Stderr, "Invalid columns value \""+column+"\"")
+			fmt.Fprintln(os.Stderr, "Valid values: "+strings.Join(ValidColumnValues[:], ", "))
+			os.Exit(1)
+		}
+	}
+
+	for _, proportion := range fen.config.PaneProportions {
+		if proportion < 0 {
+			fmt.Fprintln(os.Stderr, "Invalid pane_proportions value "+strconv.Itoa(proportion)+", can't be negative")
+			os.Exit(1)
+		}
+	}
+
+	if fen.config.PaneProportions[1] == 0 {
+		fmt.Fprintln(os.Stderr, "Invalid pane_proportions, the middle pane can't have a width of 0")
+		os.Exit(1)
+	}
+
 	app := tview.NewApplication()
 
 	helpScreen := NewHelpScreen(&fen)
@@ -261,12 +296,12 @@ func main() {
 		log.Fatal(err)
 	}
 
+	fen.filePanesFlex = tview.NewFlex().SetDirection(tview.FlexColumn)
+	fen.UpdateLayout()
+
 	flex := tview.NewFlex().SetDirection(tview.FlexRow).
 		AddItem(fen.topBar, 1, 0, false).
-		AddItem(tview.NewFlex().SetDirection(tview.FlexColumn).
-			AddItem(fen.leftPane, 0, 1, false).
-			AddItem(fen.middlePane, 0, 3, false).
-			AddItem(fen.rightPane, 0, 3, false), 0, 1, false).
+		AddItem(fen.filePanesFlex, 0, 1, false).
 		AddItem(fen.bottomBar, 1, 0, false)
 
 	pages := tview.NewPages().
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A folder that has been visited, ranked by frecency (how frequently and recently it was visited) in the jump popup
+type VisitedFolder struct {
+	Path      string  `json:"path"`
+	Rank      float64 `json:"rank"`       // Increased by 1 on every visit, and lowered for every folder when the ranks add up to more than frecencyMaxTotalRank
+	LastVisit int64   `json:"last_visit"` // Unix time
+}
+
+// What is remembered across sessions in navigation_history.json
+type navigationHistoryFile struct {
+	Folders []VisitedFolder `json:"folders"`
+	History []string        `json:"history"` // The paths in History, most recent first
+}
+
+// When the ranks of all visited folders add up to more than this, they are lowered so old folders are eventually forgotten. The same as zoxide
+const frecencyMaxTotalRank = 10000
+
+// Only the most recent entries in History are remembered across sessions
+const navigationHistoryMaxEntries = 1000
+
+// Returns how high folder is ranked in the jump popup, recently visited folders are boosted
+func frecencyScore(folder *VisitedFolder, now time.Time) float64 {
+	age := now.Sub(time.Unix(folder.LastVisit, 0))
+	switch {
+	case age < time.Hour:
+		return folder.Rank * 4
+	case age < 24*time.Hour:
+		return folder.Rank * 2
+	case age < 7*24*time.Hour:
+		return folder.Rank / 2
+	default:
+		return folder.Rank / 4
+	}
+}
+
+// Returns true if every space-separated term in query is found in path in order, ignoring case.
+// The last term has to be in the name of the folder itself, so "foo" matches "/foo" but not "/foo/bar", like in zoxide
+func frecencyMatch(path, query string) bool {
+	path = strings.ToLower(path)
+	terms := strings.Fields(strings.ToLower(query))
+
+	for i, term := range terms {
+		if i == len(terms)-1 {
+			index := strings.LastIndex(path, term)
+			return index != -1 && !strings.Contains(path[index+len(term):], string(os.PathSeparator))
+		}
+
+		index := strings.Index(path, term)
+		if index == -1 {
+			return false
+		}
+		path = path[index+len(term):]
+	}
+
+	return true
+}
+
+// Records a visit to folder, used for the jump popup
+func (fen *Fen) VisitFolder(folder string) {
+	if fen.visitedFolders == nil {
+		fen.visitedFolders = make(map[string]*VisitedFolder)
+	}
+
+	visited, ok := fen.visitedFolders[folder]
+	if !ok {
+		visited = &VisitedFolder{Path: folder}
+		fen.visitedFolders[folder] = visited
+	}
+	visited.Rank++
+	visited.LastVisit = time.Now().Unix()
+
+	totalRank := 0.0
+	for _, e := range fen.visitedFolders {
+		totalRank += e.Rank
+	}
+
+	if totalRank > frecencyMaxTotalRank {
+		factor := 0.9 * frecencyMaxTotalRank / totalRank
+		for path, e := range fen.visitedFolders {
+			e.Rank *= factor
+			if e.Rank < 1 {
+				delete(fen.visitedFolders, path)
+			}
+		}
+	}
+}
+
+// Returns the visited folders matching query, highest frecency first. Folders that no longer exist and fen.wd are left out
+func (fen *Fen) JumpCandidates(query string, maxCandidates int) []string {
+	var matches []*VisitedFolder
+	for _, e := range fen.visitedFolders {
+		if e.Path != fen.wd && frecencyMatch(e.Path, query) {
+			matches = append(matches, e)
+		}
+	}
+
+	now := time.Now()
+	slices.SortFunc(matches, func(a, b *VisitedFolder) int {
+		if result := cmp.Compare(frecencyScore(b, now), frecencyScore(a, now)); result != 0 {
+			return result
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	var candidates []string
+	for _, e := range matches {
+		if len(candidates) >= maxCandidates {
+			break
+		}
+
+		stat, err := os.Stat(e.Path)
+		if err == nil && stat.IsDir() {
+			candidates = append(candidates, e.Path)
+		}
+	}
+
+	return candidates
+}
+
+func navigationHistoryFilePath() (string, error) {
+	cacheDir, err := FenCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cacheDir, "navigation_history.json"), nil
+}
+
+// Loads the visited folders and History from the previous sessions
+func (fen *Fen) loadNavigationHistory() {
+	fen.visitedFolders = make(map[string]*VisitedFolder)
+
+	path, err := navigationHistoryFilePath()
+	if err != nil {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	// If the file is corrupt, we just start over with an empty history
+	var file navigationHistoryFile
+	if json.Unmarshal(data, &file) != nil {
+		return
+	}
+
+	for _, e := range file.Folders {
+		if filepath.IsAbs(e.Path) && e.Rank > 0 {
+			fen.visitedFolders[e.Path] = &VisitedFolder{Path: e.Path, Rank: e.Rank, LastVisit: e.LastVisit}
+		}
+	}
+
+	fen.history.historyMutex.Lock()
+	defer fen.history.historyMutex.Unlock()
+
+	for _, e := range file.History {
+		if filepath.IsAbs(e) {
+			fen.history.history = append(fen.history.history, e)
+		}
+	}
+}
+
+// Saves the visited folders and History for the next session, done when fen exits
+func (fen *Fen) saveNavigationHistory() {
+	if fen.config.NoWrite {
+		return
+	}
+
+	path, err := navigationHistoryFilePath()
+	if err != nil {
+		return
+	}
+
+	file := navigationHistoryFile{Folders: []VisitedFolder{}}
+	for _, e := range fen.visitedFolders {
+		file.Folders = append(file.Folders, *e)
+	}
+	slices.SortFunc(file.Folders, func(a, b VisitedFolder) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	fen.history.historyMutex.Lock()
+	file.History = slices.Clone(fen.history.history[:min(len(fen.history.history), navigationHistoryMaxEntries)])
+	fen.history.historyMutex.Unlock()
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return
+	}
+
+	// The folders visited can reveal a lot about the user, so only the user can read it
+	_ = os.WriteFile(path, data, 0o600)
+}
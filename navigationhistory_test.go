@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFrecencyMatch(t *testing.T) {
+	sep := string(os.PathSeparator)
+	testCases := []struct {
+		path     string
+		query    string
+		expected bool
+	}{
+		{sep + filepath.Join("home", "user", "code", "fen"), "", true},
+		{sep + filepath.Join("home", "user", "code", "fen"), "fen", true},
+		{sep + filepath.Join("home", "user", "code", "fen"), "FEN", true},
+		{sep + filepath.Join("home", "user", "code", "fen"), "code fen", true},
+		{sep + filepath.Join("home", "user", "code", "fen"), "fen code", false},
+		{sep + filepath.Join("home", "user", "code", "fen"), "code", false},
+		{sep + filepath.Join("home", "user", "fen", "fen"), "fen fen", true},
+		{sep + filepath.Join("home", "user", "code", "fen"), "user", false},
+		{sep + filepath.Join("home", "user", "code", "fen"), "xyz", false},
+	}
+
+	for _, testCase := range testCases {
+		got := frecencyMatch(testCase.path, testCase.query)
+		if got != testCase.expected {
+			t.Fatalf("Expected frecencyMatch(\"" + testCase.path + "\", \"" + testCase.query + "\") to be " + strconv.FormatBool(testCase.expected) + ", but got " + strconv.FormatBool(got))
+		}
+	}
+}
+
+func TestJumpCandidates(t *testing.T) {
+	tempDir := t.TempDir()
+	often := filepath.Join(tempDir, "often")
+	recent := filepath.Join(tempDir, "recent")
+	deleted := filepath.Join(tempDir, "deleted")
+	for _, path := range []string{often, recent} {
+		err := os.Mkdir(path, 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lastWeek := time.Now().Add(-6 * 24 * time.Hour).Unix()
+	fen := Fen{wd: tempDir}
+	fen.visitedFolders = map[string]*VisitedFolder{
+		often:   {Path: often, Rank: 10, LastVisit: lastWeek},
+		deleted: {Path: deleted, Rank: 100, LastVisit: lastWeek},
+	}
+	fen.VisitFolder(recent)
+	fen.VisitFolder(recent)
+	fen.VisitFolder(tempDir)
+
+	// 2 visits in the last hour beat 10 visits last week
+	got := fen.JumpCandidates("", 10)
+	expected := []string{recent, often}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+
+	got = fen.JumpCandidates("ofte", 10)
+	if !reflect.DeepEqual(got, []string{often}) {
+		t.Fatalf("Expected only \"often\" to match, but got %q", got)
+	}
+}
+
+func TestVisitFolderAging(t *testing.T) {
+	fen := Fen{}
+	fen.visitedFolders = map[string]*VisitedFolder{
+		"/big":   {Path: "/big", Rank: frecencyMaxTotalRank},
+		"/small": {Path: "/small", Rank: 1},
+	}
+
+	fen.VisitFolder("/new")
+
+	if _, ok := fen.visitedFolders["/small"]; ok {
+		t.Fatalf("Expected the folder with the lowest rank to be forgotten")
+	}
+	if rank := fen.visitedFolders["/big"].Rank; rank >= frecencyMaxTotalRank {
+		t.Fatalf("Expected the ranks to be lowered, but got " + strconv.FormatFloat(rank, 'f', -1, 64))
+	}
+}
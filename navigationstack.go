@@ -0,0 +1,47 @@
+package main
+
+// The working directories visited in a tab, for going back and forward like in a web browser.
+// Unlike History, which remembers the last selected path in each folder, this remembers the order they were visited in
+type NavigationStack struct {
+	entries []string // Oldest first
+	index   int      // The entry of the current working directory
+}
+
+const navigationStackMaxEntries = 100
+
+// Adds folder after the current entry, forgetting the entries we went back from
+func (s *NavigationStack) Visit(folder string) {
+	if len(s.entries) > 0 && s.entries[s.index] == folder {
+		return
+	}
+
+	if len(s.entries) > 0 {
+		s.entries = s.entries[:s.index+1]
+	}
+	s.entries = append(s.entries, folder)
+
+	if len(s.entries) > navigationStackMaxEntries {
+		s.entries = s.entries[len(s.entries)-navigationStackMaxEntries:]
+	}
+	s.index = len(s.entries) - 1
+}
+
+// Returns the folder visited before the current one, false if there is none
+func (s *NavigationStack) Back() (string, bool) {
+	if s.index <= 0 {
+		return "", false
+	}
+
+	s.index--
+	return s.entries[s.index], true
+}
+
+// Returns the folder we went back from, false if there is none
+func (s *NavigationStack) Forward() (string, bool) {
+	if s.index+1 >= len(s.entries) {
+		return "", false
+	}
+
+	s.index++
+	return s.entries[s.index], true
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestNavigationStack(t *testing.T) {
+	s := NavigationStack{}
+	if _, ok := s.Back(); ok {
+		t.Fatalf("Expected nothing to go back to")
+	}
+
+	s.Visit("/home/user/deep/path")
+	s.Visit("/home/user/deep/path") // Refreshing the same folder isn't a new entry
+	s.Visit("/")
+
+	folder, ok := s.Back()
+	if !ok || folder != "/home/user/deep/path" {
+		t.Fatalf("Expected to go back to \"/home/user/deep/path\", but got \"" + folder + "\"")
+	}
+
+	// Going back visits the folder, which shouldn't forget where we went back from
+	s.Visit(folder)
+	folder, ok = s.Forward()
+	if !ok || folder != "/" {
+		t.Fatalf("Expected to go forward to \"/\", but got \"" + folder + "\"")
+	}
+	if _, ok := s.Forward(); ok {
+		t.Fatalf("Expected nothing to go forward to")
+	}
+
+	s.Back()
+	s.Visit("/tmp")
+	if _, ok := s.Forward(); ok {
+		t.Fatalf("Expected visiting a new folder to forget the folders we went back from")
+	}
+
+	for i := 0; i < navigationStackMaxEntries*2; i++ {
+		s.Visit("/" + strconv.Itoa(i))
+	}
+	if len(s.entries) != navigationStackMaxEntries {
+		t.Fatalf("Expected " + strconv.Itoa(navigationStackMaxEntries) + " entries, but got " + strconv.Itoa(len(s.entries)))
+	}
+	folder, _ = s.Back()
+	if folder != "/"+strconv.Itoa(navigationStackMaxEntries*2-2) {
+		t.Fatalf("Expected to go back to the second to last folder, but got \"" + folder + "\"")
+	}
+}
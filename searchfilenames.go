@@ -1,9 +1,5 @@
 package main
 
-// TODO: Add sorting based on fen.config.SortBy
-// Make the file-loading thread insert at correct positions with a binary search.
-// That way we avoid re-sorting every 200ms when we re-filter and re-draw the screen
-
 /*
 	+---------------------+
 	| Search format ideas |
@@ -46,6 +42,7 @@ package main
 */
 
 import (
+	"cmp"
 	"errors"
 	"io/fs"
 	"os"
@@ -55,9 +52,11 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/charlievieth/strcase"
+	"github.com/fsnotify/fsnotify"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
@@ -78,11 +77,36 @@ type SearchFilenames struct {
 	selectedFilename                   string
 	scrollLocked                       bool
 
+	// sortKeys[i] belongs to filenames[i], they are always kept in the same order.
+	// Newly gathered files are put in pendingFilenames, and merged into filenames before filtering.
+	// That way we avoid re-sorting every 200ms when we re-filter and re-draw the screen
+	sortKeys         []searchFilenameSortKey
+	pendingFilenames []string
+	pendingSortKeys  []searchFilenameSortKey
+	sortBy           string // Valid values defined in ValidFilenameSearchSortByValues
+	sortReverse      bool
+	needsFileInfo    atomic.Bool // Whether the file-loading thread should stat files, depends on sortBy
+	basePath         string      // The filenames are relative to this path
+
+	// Only used when fen.filename_search_follow_symlinks is set.
+	// Maps the symlinked folders we descended into (relative to basePath) to their real paths
+	symlinkedDirs map[string]string
+
 	cancel               bool
+	cancelChan           chan struct{} // Closed when cancelled, so the filename index file watcher can stop
 	finishedLoading      bool
 	lastDrawTime         time.Time
 	firstDraw            bool
 	selectLastOnNextDraw bool
+	filesGathered        int // Only accessed by the file-loading thread
+}
+
+var ValidFilenameSearchSortByValues = [...]string{SORT_NONE, SORT_ALPHABETICAL, SORT_MODIFIED, SORT_SIZE, SORT_PATH_DEPTH}
+
+type searchFilenameSortKey struct {
+	modTime int64 // Unix time in nanoseconds
+	size    int64
+	hasInfo bool // False if the file hasn't been stat'ed yet
 }
 
 func NewSearchFilenames(fen *Fen) *SearchFilenames {
@@ -92,10 +116,24 @@ func NewSearchFilenames(fen *Fen) *SearchFilenames {
 		lastDrawTime:         time.Now(),
 		firstDraw:            true, // This is used so we can have a shorter delay on the first draw and longer for later ones
 		selectLastOnNextDraw: true, // Make sure the last element is selected on the first draw
+		sortBy:               SORT_ALPHABETICAL,
+		sortReverse:          fen.config.SortReverse,
+		cancelChan:           make(chan struct{}),
+		symlinkedDirs:        make(map[string]string),
 	}
 
+	if slices.Contains(ValidFilenameSearchSortByValues[:], fen.config.SortBy) {
+		s.sortBy = fen.config.SortBy
+	}
+	s.needsFileInfo.Store(filenameSearchSortNeedsFileInfo(s.sortBy))
+
 	s.wg.Add(1)
-	go s.GatherFiles(fen.wd)
+	// The filename index doesn't follow symlinked folders
+	if fen.config.FilenameSearchIndex && !fen.config.SearchFollowSymlinks {
+		go s.GatherFilesUsingIndex(fen.wd)
+	} else {
+		go s.GatherFiles(fen.wd)
+	}
 	go func() {
 		s.wg.Wait()
 
@@ -104,6 +142,7 @@ func NewSearchFilenames(fen *Fen) *SearchFilenames {
 			s.fen.app.QueueUpdateDraw(func() {
 				s.mutex.Lock()
 				{
+					s.InsertPendingFilenames()
 					s.Filter(s.searchTerm, s.fen.config.FilenameSearchCase)
 					if s.scrollLocked {
 						s.SetSelectedIndexToSelectedFilename()
@@ -184,6 +223,69 @@ func (s *SearchFilenames) SetSelectedIndexAndLockScrollIfLoading(index int) {
 	}
 }
 
+// Stops loading files, and stops the filename index from watching for file changes
+// You need to manually lock / unlock the mutex to use this function
+func (s *SearchFilenames) Cancel() {
+	if s.cancel {
+		return
+	}
+
+	s.cancel = true
+	close(s.cancelChan)
+}
+
+func (s *SearchFilenames) isCancelled() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.cancel
+}
+
+// Returns false if we were cancelled, and the file wasn't added
+func (s *SearchFilenames) appendGatheredFilename(pathName string, sortKey searchFilenameSortKey) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Are we cancelled?
+	if s.cancel {
+		return false
+	}
+
+	s.pendingFilenames = append(s.pendingFilenames, pathName)
+	s.pendingSortKeys = append(s.pendingSortKeys, sortKey)
+	return true
+}
+
+// Only call this from the file-loading thread
+func (s *SearchFilenames) queueDrawWhileLoading() {
+	s.filesGathered++
+
+	delay := 200 * time.Millisecond
+	if s.firstDraw { // A mutex is not necessary here. This variable is only accessed in one thread
+		// We use a shorter delay for the first draw so the user isn't left waiting 200ms for the first files to show up on-screen.
+		delay = 10 * time.Millisecond
+	}
+
+	// If we've loaded atleast 100 files, don't bother waiting the whole 10 milliseconds for the first draw
+	// TODO: Store the time it took to first draw, and show in some debug info in the UI
+	if time.Since(s.lastDrawTime) > delay || (s.firstDraw && s.filesGathered >= 100) {
+		s.firstDraw = false
+
+		s.fen.app.QueueUpdateDraw(func() {
+			s.mutex.Lock()
+			{
+				s.InsertPendingFilenames()
+				s.Filter(s.searchTerm, s.fen.config.FilenameSearchCase)
+				if s.scrollLocked {
+					s.SetSelectedIndexToSelectedFilename()
+				}
+			}
+			s.mutex.Unlock()
+		})
+
+		s.lastDrawTime = time.Now()
+	}
+}
+
 func (s *SearchFilenames) GatherFiles(pathInput string) {
 	// EvalSymlinks is a recursive, potentially slow function.
 	// We can afford it to be slow, because it is only ran once when you open the search filenames popup.
@@ -193,6 +295,10 @@ func (s *SearchFilenames) GatherFiles(pathInput string) {
 		return
 	}
 
+	s.mutex.Lock()
+	s.basePath = basePathSymlinkResolved
+	s.mutex.Unlock()
+
 	var basePathLength int
 	if basePathSymlinkResolved == "." {
 		basePathLength = 0
@@ -211,7 +317,43 @@ func (s *SearchFilenames) GatherFiles(pathInput string) {
 		}
 	}
 
-	// FIXME: Unfortunately, WalkDir doesn't resolve symlink directories. Do you think anyone will notice? :3
+	if s.fen.config.SearchFollowSymlinks {
+		// Unhandled error
+		_ = WalkDirFollowingSymlinks(basePathSymlinkResolved, func(path, realPath string, d fs.DirEntry, isDir bool) error {
+			// Hide files/folders starting with '.' if hidden files are hidden
+			if !s.fen.config.HiddenFiles && d.Name()[0] == '.' {
+				if isDir {
+					return filepath.SkipDir
+				} else {
+					return nil
+				}
+			}
+
+			if isDir {
+				if d.Type()&os.ModeSymlink != 0 {
+					s.mutex.Lock()
+					s.symlinkedDirs[path[basePathLength:]] = realPath
+					s.mutex.Unlock()
+				}
+				return nil
+			}
+
+			var sortKey searchFilenameSortKey
+			if s.needsFileInfo.Load() {
+				sortKey = sortKeyFromDirEntry(d)
+			}
+
+			if !s.appendGatheredFilename(path[basePathLength:], sortKey) {
+				return filepath.SkipAll
+			}
+
+			s.queueDrawWhileLoading()
+			return nil
+		})
+
+		s.wg.Done()
+		return
+	}
 
 	// Unhandled error
 	_ = filepath.WalkDir(basePathSymlinkResolved, func(path string, d fs.DirEntry, err error) error {
@@ -233,48 +375,613 @@ func (s *SearchFilenames) GatherFiles(pathInput string) {
 			return nil
 		}
 
-		s.mutex.Lock()
-		{
-			// Are we cancelled?
-			if s.cancel {
-				s.mutex.Unlock()
-				return filepath.SkipAll
-			}
+		// Only stat the file when the sorting needs it, d.Info() is slow
+		var sortKey searchFilenameSortKey
+		if s.needsFileInfo.Load() {
+			sortKey = sortKeyFromDirEntry(d)
+		}
 
-			pathName := path[basePathLength:]
-			s.filenames = append(s.filenames, pathName)
+		if !s.appendGatheredFilename(path[basePathLength:], sortKey) {
+			return filepath.SkipAll
 		}
-		s.mutex.Unlock()
 
-		delay := 200 * time.Millisecond
-		if s.firstDraw { // A mutex is not necessary here. This variable is only accessed in one thread
-			// We use a shorter delay for the first draw so the user isn't left waiting 200ms for the first files to show up on-screen.
-			delay = 10 * time.Millisecond
+		s.queueDrawWhileLoading()
+		return nil
+	})
+
+	s.wg.Done()
+}
+
+// Like filepath.WalkDir(), but also descends into symlinked folders.
+// Every folder is only visited once (compared by device and inode, or by real path on Windows), so symlink loops are skipped.
+// realPath is the path with all symlinked folders resolved, and isDir is true for symlinks to folders.
+// The root itself is not passed to fn, and fn can return filepath.SkipDir or filepath.SkipAll like with filepath.WalkDir()
+func WalkDirFollowingSymlinks(root string, fn func(path, realPath string, d fs.DirEntry, isDir bool) error) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+
+	visitedIDs := make(map[FileID]bool)
+	visitedRealPaths := make(map[string]bool)
+
+	// Returns false if the folder was already visited
+	markVisited := func(realPath string, stat fs.FileInfo) bool {
+		id, ok := GetFileID(stat)
+		if ok {
+			if visitedIDs[id] {
+				return false
+			}
+			visitedIDs[id] = true
+			return true
 		}
 
-		// If we've loaded atleast 100 files, don't bother waiting the whole 10 milliseconds for the first draw
-		// TODO: Store the time it took to first draw, and show in some debug info in the UI
-		if time.Since(s.lastDrawTime) > delay || (s.firstDraw && len(s.filenames) >= 100) {
-			s.firstDraw = false
+		if visitedRealPaths[realPath] {
+			return false
+		}
+		visitedRealPaths[realPath] = true
+		return true
+	}
 
-			s.fen.app.QueueUpdateDraw(func() {
-				s.mutex.Lock()
-				{
-					s.Filter(s.searchTerm, s.fen.config.FilenameSearchCase)
-					if s.scrollLocked {
-						s.SetSelectedIndexToSelectedFilename()
+	rootStat, err := os.Stat(realRoot)
+	if err != nil {
+		return err
+	}
+	markVisited(realRoot, rootStat)
+
+	var walk func(path, realPath string) error
+	walk = func(path, realPath string) error {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil // Skip folders we can't read, like filepath.WalkDir() with filepath.SkipDir
+		}
+
+		for _, entry := range entries {
+			entryPath := filepath.Join(path, entry.Name())
+			entryRealPath := filepath.Join(realPath, entry.Name())
+
+			isDir := entry.IsDir()
+			var dirStat fs.FileInfo
+			if entry.Type()&os.ModeSymlink != 0 {
+				// Broken symlinks and symlinks to files are treated as files
+				stat, err := os.Stat(entryPath)
+				if err == nil && stat.IsDir() {
+					entryRealPath, err = filepath.EvalSymlinks(entryPath)
+					if err == nil {
+						isDir = true
+						dirStat = stat
 					}
 				}
-				s.mutex.Unlock()
-			})
+			} else if isDir {
+				dirStat, err = entry.Info()
+				if err != nil {
+					continue
+				}
+			}
+
+			if isDir && !markVisited(entryRealPath, dirStat) {
+				continue
+			}
 
-			s.lastDrawTime = time.Now()
+			err := fn(entryPath, entryRealPath, entry, isDir)
+			if err == filepath.SkipAll {
+				return err
+			}
+			if err == filepath.SkipDir || !isDir {
+				continue
+			}
+
+			err = walk(entryPath, entryRealPath)
+			if err == filepath.SkipAll {
+				return err
+			}
 		}
 
 		return nil
+	}
+
+	err = walk(root, realRoot)
+	if err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// Returns the path of filename with all symlinked folders resolved, or an empty string if it isn't inside a symlinked folder
+// You need to manually lock / unlock the mutex to use this function
+func (s *SearchFilenames) realPathOf(filename string) string {
+	if len(s.symlinkedDirs) == 0 {
+		return ""
+	}
+
+	// The deepest symlinked folder has its real path fully resolved, so we look for the nearest parent folder
+	for dir := filepath.Dir(filename); dir != "." && dir != string(os.PathSeparator); dir = filepath.Dir(dir) {
+		realDir, ok := s.symlinkedDirs[dir]
+		if ok {
+			return filepath.Join(realDir, filename[len(dir)+1:])
+		}
+	}
+
+	return ""
+}
+
+// Like GatherFiles(), but starts with the files stored in the filename index, and only reads the folders that changed since then.
+// After loading, it keeps watching the indexed folders for changes until cancelled.
+func (s *SearchFilenames) GatherFilesUsingIndex(pathInput string) {
+	basePathSymlinkResolved, err := filepath.EvalSymlinks(pathInput)
+	if err != nil {
+		s.fen.bottomBar.TemporarilyShowTextInstead(err.Error())
+		s.wg.Done()
+		return
+	}
+
+	s.mutex.Lock()
+	s.basePath = basePathSymlinkResolved
+	s.mutex.Unlock()
+
+	hiddenFiles := s.fen.config.HiddenFiles
+	indexPath, indexPathErr := FilenameIndexPath(basePathSymlinkResolved, hiddenFiles)
+
+	var index *FilenameIndex
+	if indexPathErr == nil {
+		index, err = LoadFilenameIndex(indexPath, basePathSymlinkResolved, hiddenFiles)
+	}
+	if indexPathErr != nil || err != nil {
+		index = NewFilenameIndex(basePathSymlinkResolved, hiddenFiles)
+	}
+
+	index.ForEachFile(func(path string) bool {
+		if !s.appendGatheredFilename(path, s.sortKeyFromPath(path)) {
+			return false
+		}
+
+		s.queueDrawWhileLoading()
+		return true
+	})
+
+	var removedFilenames []string
+	index.Refresh("", true, FilenameIndexRefreshCallbacks{
+		FileAdded: func(path string) {
+			if s.appendGatheredFilename(path, s.sortKeyFromPath(path)) {
+				s.queueDrawWhileLoading()
+			}
+		},
+		FileRemoved: func(path string) {
+			removedFilenames = append(removedFilenames, path)
+		},
+		Cancelled: s.isCancelled,
 	})
 
+	if len(removedFilenames) > 0 {
+		// This is queued before the "All files have been loaded" update in NewSearchFilenames()
+		s.fen.app.QueueUpdateDraw(func() {
+			s.mutex.Lock()
+			s.ApplyFilenameChanges(removedFilenames)
+			s.mutex.Unlock()
+		})
+	}
+
+	saveIndex := func() {
+		if indexPathErr != nil || s.fen.config.NoWrite {
+			return
+		}
+
+		err := index.Save(indexPath)
+		if err != nil {
+			s.fen.bottomBar.TemporarilyShowTextInstead("Failed to save filename index: " + err.Error())
+		}
+	}
+
+	saveIndex()
 	s.wg.Done()
+
+	if s.isCancelled() {
+		return
+	}
+
+	s.watchIndexedDirectories(index)
+	saveIndex()
+}
+
+// Keeps the filename index and the search results up-to-date with file changes until cancelled
+func (s *SearchFilenames) watchIndexedDirectories(index *FilenameIndex) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return
+	}
+	defer watcher.Close()
+
+	watchedCount := 0
+	watch := func(relDir string) {
+		if watchedCount >= filenameIndexMaxWatchedDirectories {
+			return
+		}
+
+		if watcher.Add(filepath.Join(index.Root, relDir)) == nil {
+			watchedCount++
+		}
+	}
+
+	for _, relDir := range index.DirectoriesByDepth() {
+		watch(relDir)
+	}
+
+	changedDirs := make(map[string]bool)
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.cancelChan:
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+
+			relDir, err := filepath.Rel(index.Root, filepath.Dir(event.Name))
+			if err != nil {
+				continue
+			}
+			if relDir == "." {
+				relDir = ""
+			}
+
+			changedDirs[relDir] = true
+		case _, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+			if len(changedDirs) == 0 {
+				continue
+			}
+
+			added := false
+			var removedFilenames []string
+			for relDir := range changedDirs {
+				index.Refresh(relDir, false, FilenameIndexRefreshCallbacks{
+					FileAdded: func(path string) {
+						if s.appendGatheredFilename(path, s.sortKeyFromPath(path)) {
+							added = true
+						}
+					},
+					FileRemoved: func(path string) {
+						removedFilenames = append(removedFilenames, path)
+					},
+					DirectoryAdded: watch,
+					Cancelled:      s.isCancelled,
+				})
+			}
+			clear(changedDirs)
+
+			if added || len(removedFilenames) > 0 {
+				s.fen.app.QueueUpdateDraw(func() {
+					s.mutex.Lock()
+					s.ApplyFilenameChanges(removedFilenames)
+					s.mutex.Unlock()
+				})
+			}
+		}
+	}
+}
+
+// Inserts the pending filenames and removes removedFilenames, keeping the selected filename selected.
+// You need to manually lock / unlock the mutex to use this function
+func (s *SearchFilenames) ApplyFilenameChanges(removedFilenames []string) {
+	selectedFilename, selectedErr := s.GetSelectedFilename()
+	// While loading, we only keep the selection if the user moved it, otherwise the last filename should stay selected
+	keepSelection := selectedErr == nil && (s.scrollLocked || s.finishedLoading)
+
+	s.InsertPendingFilenames()
+
+	if len(removedFilenames) > 0 {
+		toRemove := make(map[string]bool, len(removedFilenames))
+		for _, filename := range removedFilenames {
+			toRemove[filename] = true
+		}
+
+		i := 0
+		for j, filename := range s.filenames {
+			if toRemove[filename] {
+				continue
+			}
+
+			s.filenames[i] = filename
+			s.sortKeys[i] = s.sortKeys[j]
+			i++
+		}
+		s.filenames = s.filenames[:i]
+		s.sortKeys = s.sortKeys[:i]
+	}
+
+	s.refilterKeepingSelection(selectedFilename, keepSelection)
+}
+
+// Filters all the filenames again, this is needed after filenames have been re-ordered or removed,
+// since the indices in filenamesFilteredIndices are no longer valid.
+// You need to manually lock / unlock the mutex to use this function
+func (s *SearchFilenames) refilterKeepingSelection(selectedFilename string, keepSelection bool) {
+	s.selectedFilename = selectedFilename
+	s.scrollLocked = keepSelection
+
+	searchTerm := s.searchTerm
+	s.searchTerm = "" // Makes Filter() filter all the filenames instead of only the previous results
+	s.Filter(searchTerm, s.fen.config.FilenameSearchCase)
+
+	if s.scrollLocked {
+		s.SetSelectedIndexToSelectedFilename()
+	}
+
+	// The selected filename was removed
+	if !s.scrollLocked {
+		s.selectedFilenameIndex = max(0, s.filenamesLen()-1)
+	}
+
+	if s.finishedLoading {
+		s.scrollLocked = false
+	}
+}
+
+func (s *SearchFilenames) filenamesLen() int {
+	if s.searchTerm == "" {
+		return len(s.filenames)
+	}
+	return len(s.filenamesFilteredIndices)
+}
+
+func filenameSearchSortNeedsFileInfo(sortBy string) bool {
+	return sortBy == SORT_MODIFIED || sortBy == SORT_SIZE
+}
+
+func sortKeyFromFileInfo(info fs.FileInfo) searchFilenameSortKey {
+	return searchFilenameSortKey{
+		modTime: info.ModTime().UnixNano(),
+		size:    info.Size(),
+		hasInfo: true,
+	}
+}
+
+// Only stats the file when the current sorting needs it
+// The path is relative to s.basePath
+func (s *SearchFilenames) sortKeyFromPath(path string) searchFilenameSortKey {
+	if !s.needsFileInfo.Load() {
+		return searchFilenameSortKey{}
+	}
+
+	s.mutex.Lock()
+	basePath := s.basePath
+	s.mutex.Unlock()
+
+	info, err := os.Lstat(filepath.Join(basePath, path))
+	if err != nil {
+		return searchFilenameSortKey{hasInfo: true} // Don't try again
+	}
+
+	return sortKeyFromFileInfo(info)
+}
+
+func sortKeyFromDirEntry(d fs.DirEntry) searchFilenameSortKey {
+	info, err := d.Info()
+	if err != nil {
+		return searchFilenameSortKey{hasInfo: true} // Don't try again
+	}
+
+	return sortKeyFromFileInfo(info)
+}
+
+// Returns a comparison function for filenames in the search filenames popup, or nil for SORT_NONE.
+// Equal keys are compared by path, so the order is always deterministic.
+// The valid values for sortBy are defined in ValidFilenameSearchSortByValues
+func FilenameSearchCompareFunc(sortBy string, reverse bool) func(aName string, aKey searchFilenameSortKey, bName string, bKey searchFilenameSortKey) int {
+	var compare func(aName string, aKey searchFilenameSortKey, bName string, bKey searchFilenameSortKey) int
+
+	switch sortBy {
+	case SORT_NONE:
+		return nil
+	case SORT_ALPHABETICAL:
+		compare = func(aName string, aKey searchFilenameSortKey, bName string, bKey searchFilenameSortKey) int {
+			return strings.Compare(aName, bName)
+		}
+	case SORT_MODIFIED:
+		compare = func(aName string, aKey searchFilenameSortKey, bName string, bKey searchFilenameSortKey) int {
+			if aKey.modTime != bKey.modTime {
+				return cmp.Compare(aKey.modTime, bKey.modTime)
+			}
+			return strings.Compare(aName, bName)
+		}
+	case SORT_SIZE:
+		compare = func(aName string, aKey searchFilenameSortKey, bName string, bKey searchFilenameSortKey) int {
+			if aKey.size != bKey.size {
+				return cmp.Compare(aKey.size, bKey.size)
+			}
+			return strings.Compare(aName, bName)
+		}
+	case SORT_PATH_DEPTH:
+		compare = func(aName string, aKey searchFilenameSortKey, bName string, bKey searchFilenameSortKey) int {
+			aDepth := strings.Count(aName, string(os.PathSeparator))
+			bDepth := strings.Count(bName, string(os.PathSeparator))
+			if aDepth != bDepth {
+				return cmp.Compare(aDepth, bDepth)
+			}
+			return strings.Compare(aName, bName)
+		}
+	default:
+		panic("Invalid filename search sort value \"" + sortBy + "\"")
+	}
+
+	if reverse {
+		return func(aName string, aKey searchFilenameSortKey, bName string, bKey searchFilenameSortKey) int {
+			return compare(bName, bKey, aName, aKey)
+		}
+	}
+
+	return compare
+}
+
+// Sorts filenames and sortKeys together in-place, they need to be the same length
+func SortFilenamesAndKeys(filenames []string, sortKeys []searchFilenameSortKey, compare func(aName string, aKey searchFilenameSortKey, bName string, bKey searchFilenameSortKey) int) {
+	if len(filenames) != len(sortKeys) {
+		panic("In SortFilenamesAndKeys(): Length of filenames and sortKeys weren't the same")
+	}
+
+	indices := make([]int, len(filenames))
+	for i := range indices {
+		indices[i] = i
+	}
+
+	slices.SortFunc(indices, func(a, b int) int {
+		return compare(filenames[a], sortKeys[a], filenames[b], sortKeys[b])
+	})
+
+	sortedFilenames := make([]string, len(filenames))
+	sortedKeys := make([]searchFilenameSortKey, len(sortKeys))
+	for i, index := range indices {
+		sortedFilenames[i] = filenames[index]
+		sortedKeys[i] = sortKeys[index]
+	}
+
+	copy(filenames, sortedFilenames)
+	copy(sortKeys, sortedKeys)
+}
+
+// Merges the sorted newFilenames into the sorted filenames, returning the grown slices.
+// The merge is done backwards in-place, so we only allocate when the slices need to grow.
+func MergeSortedFilenames(filenames []string, sortKeys []searchFilenameSortKey, newFilenames []string, newSortKeys []searchFilenameSortKey, compare func(aName string, aKey searchFilenameSortKey, bName string, bKey searchFilenameSortKey) int) ([]string, []searchFilenameSortKey) {
+	oldLength := len(filenames)
+	filenames = append(filenames, newFilenames...)
+	sortKeys = append(sortKeys, newSortKeys...)
+
+	i := oldLength - 1         // Last element of the old filenames
+	j := len(newFilenames) - 1 // Last element of the new filenames
+	for k := len(filenames) - 1; j >= 0; k-- {
+		if i >= 0 && compare(filenames[i], sortKeys[i], newFilenames[j], newSortKeys[j]) > 0 {
+			filenames[k] = filenames[i]
+			sortKeys[k] = sortKeys[i]
+			i--
+		} else {
+			filenames[k] = newFilenames[j]
+			sortKeys[k] = newSortKeys[j]
+			j--
+		}
+	}
+
+	return filenames, sortKeys
+}
+
+// Moves the files gathered since the last call into s.filenames at their sorted positions.
+// You need to manually lock / unlock the mutex to use this function
+func (s *SearchFilenames) InsertPendingFilenames() {
+	if len(s.pendingFilenames) == 0 {
+		return
+	}
+
+	compare := FilenameSearchCompareFunc(s.sortBy, s.sortReverse)
+	if compare == nil {
+		s.filenames = append(s.filenames, s.pendingFilenames...)
+		s.sortKeys = append(s.sortKeys, s.pendingSortKeys...)
+	} else {
+		SortFilenamesAndKeys(s.pendingFilenames, s.pendingSortKeys, compare)
+		s.filenames, s.sortKeys = MergeSortedFilenames(s.filenames, s.sortKeys, s.pendingFilenames, s.pendingSortKeys, compare)
+	}
+
+	s.pendingFilenames = nil
+	s.pendingSortKeys = nil
+}
+
+// Returns the filenames (including the pending ones) we haven't stat'ed yet, when the current sorting needs it.
+// You need to manually lock / unlock the mutex to use this function
+func (s *SearchFilenames) filenamesMissingFileInfo() []string {
+	if !filenameSearchSortNeedsFileInfo(s.sortBy) {
+		return nil
+	}
+
+	var missing []string
+	for i, key := range s.sortKeys {
+		if !key.hasInfo {
+			missing = append(missing, s.filenames[i])
+		}
+	}
+	for i, key := range s.pendingSortKeys {
+		if !key.hasInfo {
+			missing = append(missing, s.pendingFilenames[i])
+		}
+	}
+
+	return missing
+}
+
+// Stats filenames in a separate thread, since there can be millions of them.
+// When done, their sort keys are updated and the filenames are sorted again
+func (s *SearchFilenames) statFilesInBackground(basePath string, filenames []string) {
+	go func() {
+		sortKeys := make(map[string]searchFilenameSortKey, len(filenames))
+		for _, filename := range filenames {
+			if s.isCancelled() {
+				return
+			}
+
+			info, err := os.Lstat(filepath.Join(basePath, filename))
+			if err != nil {
+				sortKeys[filename] = searchFilenameSortKey{hasInfo: true} // Don't try again
+				continue
+			}
+			sortKeys[filename] = sortKeyFromFileInfo(info)
+		}
+
+		s.fen.app.QueueUpdateDraw(func() {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+
+			if s.cancel {
+				return
+			}
+
+			for i, filename := range s.filenames {
+				if key, ok := sortKeys[filename]; ok && !s.sortKeys[i].hasInfo {
+					s.sortKeys[i] = key
+				}
+			}
+			for i, filename := range s.pendingFilenames {
+				if key, ok := sortKeys[filename]; ok && !s.pendingSortKeys[i].hasInfo {
+					s.pendingSortKeys[i] = key
+				}
+			}
+
+			selectedFilename, selectedErr := s.GetSelectedFilename()
+			if compare := FilenameSearchCompareFunc(s.sortBy, s.sortReverse); compare != nil {
+				SortFilenamesAndKeys(s.filenames, s.sortKeys, compare)
+			}
+			s.refilterKeepingSelection(selectedFilename, selectedErr == nil)
+		})
+	}()
+}
+
+// Changes to the next sorting in ValidFilenameSearchSortByValues, without restarting the file-loading thread.
+// The selected filename stays selected. If the files need to be stat'ed first, they are sorted again once that is done.
+// You need to manually lock / unlock the mutex to use this function
+func (s *SearchFilenames) CycleSortBy() {
+	selectedFilename, selectedErr := s.GetSelectedFilename()
+
+	index := slices.Index(ValidFilenameSearchSortByValues[:], s.sortBy)
+	s.sortBy = ValidFilenameSearchSortByValues[(index+1)%len(ValidFilenameSearchSortByValues)]
+	s.needsFileInfo.Store(filenameSearchSortNeedsFileInfo(s.sortBy))
+
+	if missing := s.filenamesMissingFileInfo(); len(missing) > 0 {
+		s.statFilesInBackground(s.basePath, missing)
+	}
+
+	// With SORT_NONE, we just keep the current order
+	compare := FilenameSearchCompareFunc(s.sortBy, s.sortReverse)
+	if compare != nil {
+		SortFilenamesAndKeys(s.filenames, s.sortKeys, compare)
+	}
+
+	s.refilterKeepingSelection(selectedFilename, selectedErr == nil)
 }
 
 // You need to manually lock / unlock the mutex to use this function
@@ -447,6 +1154,11 @@ func (s *SearchFilenames) Draw(screen tcell.Screen) {
 
 			screen.SetContent(x+runeIndex, yPos, c, nil, style.Foreground(color))
 		}
+
+		realPath := s.realPathOf(filename)
+		if realPath != "" && runeIndex+1 < w-1 {
+			tview.Print(screen, "[::d] -> "+tview.Escape(realPath), x+runeIndex+1, yPos, w-runeIndex-1, tview.AlignLeft, tcell.ColorDefault)
+		}
 	}
 
 	if s.searchTerm == "" {
@@ -479,7 +1191,13 @@ func (s *SearchFilenames) Draw(screen tcell.Screen) {
 
 	matchCountStr := strconv.FormatInt(int64(filenamesLen), 10)
 	filesTotalCountStr := strconv.FormatInt(int64(len(s.filenames)), 10)
-	tview.Print(screen, matchCountStr+" / "+filesTotalCountStr+" files", x, bottomY, w, tview.AlignLeft, color)
+	_, countPrintedLength := tview.Print(screen, matchCountStr+" / "+filesTotalCountStr+" files", x, bottomY, w, tview.AlignLeft, color)
+
+	sortText := "[::d]sorted by " + s.sortBy + " (^S)"
+	if s.sortBy == SORT_NONE {
+		sortText = "[::d]unsorted (^S)"
+	}
+	tview.Print(screen, sortText, x+countPrintedLength+1, bottomY, w-countPrintedLength-1, tview.AlignLeft, tcell.ColorDefault)
 
 	var scrollPercentageStr string
 	if filenamesLen < h {
@@ -2,6 +2,11 @@ package main
 
 import (
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"testing"
 	"time"
 )
@@ -60,3 +65,121 @@ func TestFilter(t *testing.T) {
 	totalDuration = time.Since(totalStart)
 	fmt.Println(" " + totalDuration.String())
 }
+
+func TestMergeSortedFilenames(t *testing.T) {
+	compare := FilenameSearchCompareFunc(SORT_ALPHABETICAL, false)
+
+	filenames := []string{"a", "c", "e"}
+	sortKeys := make([]searchFilenameSortKey, len(filenames))
+	newFilenames := []string{"f", "b", "d", "0"}
+	newSortKeys := make([]searchFilenameSortKey, len(newFilenames))
+
+	SortFilenamesAndKeys(newFilenames, newSortKeys, compare)
+	filenames, sortKeys = MergeSortedFilenames(filenames, sortKeys, newFilenames, newSortKeys, compare)
+
+	expected := []string{"0", "a", "b", "c", "d", "e", "f"}
+	if !slices.Equal(filenames, expected) {
+		t.Fatal("Expected", expected, "but got", filenames)
+	}
+
+	if len(sortKeys) != len(filenames) {
+		t.Fatal("Expected sortKeys to have the same length as filenames")
+	}
+}
+
+func TestFilenameSearchCompareFunc(t *testing.T) {
+	sep := string(os.PathSeparator)
+	filenames := []string{"b" + sep + "c" + sep + "d", "a" + sep + "b", "z", "small", "big"}
+	sortKeys := []searchFilenameSortKey{
+		{modTime: 5, size: 3, hasInfo: true},
+		{modTime: 1, size: 2, hasInfo: true},
+		{modTime: 3, size: 2, hasInfo: true},
+		{modTime: 4, size: 1, hasInfo: true},
+		{modTime: 2, size: 100, hasInfo: true},
+	}
+
+	type TestCase struct {
+		sortBy   string
+		reverse  bool
+		expected []string
+	}
+
+	tests := []TestCase{
+		{SORT_ALPHABETICAL, false, []string{"a" + sep + "b", "b" + sep + "c" + sep + "d", "big", "small", "z"}},
+		{SORT_ALPHABETICAL, true, []string{"z", "small", "big", "b" + sep + "c" + sep + "d", "a" + sep + "b"}},
+		{SORT_MODIFIED, false, []string{"a" + sep + "b", "big", "z", "small", "b" + sep + "c" + sep + "d"}},
+		{SORT_SIZE, false, []string{"small", "a" + sep + "b", "z", "b" + sep + "c" + sep + "d", "big"}},
+		{SORT_PATH_DEPTH, false, []string{"big", "small", "z", "a" + sep + "b", "b" + sep + "c" + sep + "d"}},
+	}
+
+	for _, test := range tests {
+		names := slices.Clone(filenames)
+		keys := slices.Clone(sortKeys)
+		SortFilenamesAndKeys(names, keys, FilenameSearchCompareFunc(test.sortBy, test.reverse))
+		if !slices.Equal(names, test.expected) {
+			t.Fatal("Sorting by", test.sortBy, "reverse:", test.reverse, "expected", test.expected, "but got", names)
+		}
+	}
+}
+
+func TestWalkDirFollowingSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Creating symlinks requires special privileges on Windows")
+	}
+
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	err := os.Mkdir(filepath.Join(root, "dir"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(root, "dir", "file.txt"), nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(outside, "outside.txt"), nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A loop back to the root, and a symlinked folder outside the root
+	err = os.Symlink(root, filepath.Join(root, "dir", "loop"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Symlink(outside, filepath.Join(root, "linked"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	realOutside, err := filepath.EvalSymlinks(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	err = WalkDirFollowingSymlinks(root, func(path, realPath string, d fs.DirEntry, isDir bool) error {
+		if !isDir {
+			relPath, _ := filepath.Rel(root, path)
+			files[relPath] = realPath
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 {
+		t.Fatal("Expected 2 files, but got", files)
+	}
+
+	realPath, ok := files[filepath.Join("linked", "outside.txt")]
+	if !ok || realPath != filepath.Join(realOutside, "outside.txt") {
+		t.Fatal("Expected linked/outside.txt to have the real path", filepath.Join(realOutside, "outside.txt"), "but got", files)
+	}
+
+	if _, ok := files[filepath.Join("dir", "file.txt")]; !ok {
+		t.Fatal("Expected dir/file.txt to be found, but got", files)
+	}
+}
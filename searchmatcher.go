@@ -0,0 +1,202 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charlievieth/strcase"
+)
+
+// The ways a folder can be filtered with the "|" popup
+const (
+	FILTER_SUBSTRING = "substring"
+	FILTER_GLOB      = "glob"
+	FILTER_REGEX     = "regex"
+)
+
+// Matches entry names for the "/" search in the middle pane
+// Also used for the per-folder filters, see Fen.SetFilter()
+type SearchMatcher struct {
+	term          string
+	regex         *regexp.Regexp // nil when not searching with a regular expression
+	glob          bool           // The term is a pattern matching the entire name, see filepath.Match()
+	caseSensitive bool
+}
+
+func isCaseSensitive(term string, caseSensitivity string) bool {
+	switch caseSensitivity {
+	case CASE_SENSITIVE:
+		return true
+	case CASE_INSENSITIVE:
+		return false
+	case CASE_SMART:
+		return strings.IndexFunc(term, unicode.IsUpper) != -1
+	}
+
+	panic("isCaseSensitive(): Invalid fen.search_case value: " + caseSensitivity)
+}
+
+// The valid values for caseSensitivity are defined in ValidSearchCaseValues (fen.go)
+// With CASE_SMART, the search is case-sensitive only if the search term contains an uppercase letter
+func NewSearchMatcher(term string, useRegex bool, caseSensitivity string) (*SearchMatcher, error) {
+	caseSensitive := isCaseSensitive(term, caseSensitivity)
+
+	matcher := &SearchMatcher{term: term, caseSensitive: caseSensitive}
+	if useRegex {
+		expression := term
+		if !caseSensitive {
+			expression = "(?i)" + expression
+		}
+
+		var err error
+		matcher.regex, err = regexp.Compile(expression)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return matcher, nil
+}
+
+// Like NewSearchMatcher(), but pattern has to match the entire name, like "*.log"
+func NewGlobSearchMatcher(pattern string, caseSensitivity string) (*SearchMatcher, error) {
+	caseSensitive := isCaseSensitive(pattern, caseSensitivity)
+
+	// filepath.Match() only reports a bad pattern while matching, so we check it once here
+	_, err := filepath.Match(pattern, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchMatcher{term: pattern, glob: true, caseSensitive: caseSensitive}, nil
+}
+
+// Returns the byte index in name where a glob pattern starts matching.
+// In file list mode the names are paths, and since "*" doesn't match path separators,
+// a pattern without a path separator is matched against the last path element, like in .gitignore files
+func (m *SearchMatcher) globMatchStart(name string) int {
+	if strings.ContainsRune(m.term, os.PathSeparator) {
+		return 0
+	}
+
+	return strings.LastIndexByte(name, os.PathSeparator) + 1
+}
+
+func (m *SearchMatcher) Match(name string) bool {
+	if m.regex != nil {
+		return m.regex.MatchString(name)
+	}
+
+	if m.glob {
+		name = name[m.globMatchStart(name):]
+
+		var matched bool
+		if m.caseSensitive {
+			matched, _ = filepath.Match(m.term, name)
+		} else {
+			matched, _ = filepath.Match(strings.ToLower(m.term), strings.ToLower(name))
+		}
+		return matched
+	}
+
+	if m.caseSensitive {
+		return strings.Contains(name, m.term)
+	}
+	return strcase.Contains(name, m.term)
+}
+
+// Returns the start and end byte indices of the matches in name, used for highlighting
+func (m *SearchMatcher) MatchRanges(name string) [][]int {
+	if m.regex != nil {
+		return m.regex.FindAllStringIndex(name, 100) // Stop after 100 matches
+	}
+
+	if m.glob {
+		if m.Match(name) {
+			return [][]int{{m.globMatchStart(name), len(name)}}
+		}
+		return nil
+	}
+
+	if m.term == "" {
+		return nil
+	}
+
+	indexFunc := strcase.Index
+	if m.caseSensitive {
+		indexFunc = strings.Index
+	}
+
+	// A case-insensitive match can have a different byte length than the term, like "K" (the Kelvin sign) matching "k".
+	// Every rune of the term matches a single rune, so the match has as many runes as the term
+	termRunes := utf8.RuneCountInString(m.term)
+
+	var ranges [][]int
+	for i := 0; i < len(name) && len(ranges) < 100; { // Stop after 100 matches
+		found := indexFunc(name[i:], m.term)
+		if found == -1 {
+			break
+		}
+
+		start := i + found
+		end := start
+		for j := 0; j < termRunes && end < len(name); j++ {
+			_, size := utf8.DecodeRuneInString(name[end:])
+			end += size
+		}
+
+		ranges = append(ranges, []int{start, end})
+		i = end
+	}
+	return ranges
+}
+
+// Returns the index of the next matching element after start (or before, if forward is false), wrapping around.
+// If includeStart is true, start itself can be returned. Returns false if nothing matched
+func NextMatchingIndex(length, start int, forward, includeStart bool, matches func(i int) bool) (int, bool) {
+	if length <= 0 {
+		return 0, false
+	}
+
+	step := 1
+	if !forward {
+		step = -1
+	}
+
+	start = max(0, min(length-1, start))
+	i := start
+	if !includeStart {
+		i = (i + step + length) % length
+	}
+
+	for checked := 0; checked < length; checked++ {
+		if matches(i) {
+			return i, true
+		}
+		i = (i + step + length) % length
+	}
+
+	return 0, false
+}
+
+// Returns the filter mode of a per-folder filter, one of the FILTER_ constants
+func (m *SearchMatcher) filterMode() string {
+	if m.glob {
+		return FILTER_GLOB
+	} else if m.regex != nil {
+		return FILTER_REGEX
+	}
+	return FILTER_SUBSTRING
+}
+
+// Returns a SearchMatcher for a per-folder filter, mode is one of the FILTER_ constants
+func NewFilterSearchMatcher(term, mode, caseSensitivity string) (*SearchMatcher, error) {
+	if mode == FILTER_GLOB {
+		return NewGlobSearchMatcher(term, caseSensitivity)
+	}
+	return NewSearchMatcher(term, mode == FILTER_REGEX, caseSensitivity)
+}
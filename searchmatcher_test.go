@@ -0,0 +1,128 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSearchMatcher(t *testing.T) {
+	type TestCase struct {
+		term            string
+		useRegex        bool
+		caseSensitivity string
+		name            string
+		expected        bool
+	}
+
+	tests := []TestCase{
+		{"log", false, CASE_INSENSITIVE, "Server.LOG", true},
+		{"log", false, CASE_SENSITIVE, "Server.LOG", false},
+		{"log", false, CASE_SMART, "Server.LOG", true},
+		{"Log", false, CASE_SMART, "server.log", false},
+		{"Log", false, CASE_SMART, "server.Log", true},
+		{`^app-\d+\.log$`, true, CASE_INSENSITIVE, "APP-12.log", true},
+		{`^app-\d+\.log$`, true, CASE_SENSITIVE, "APP-12.log", false},
+		{`^app-\d+\.log$`, true, CASE_SMART, "app-12.log.gz", false},
+	}
+
+	for _, test := range tests {
+		matcher, err := NewSearchMatcher(test.term, test.useRegex, test.caseSensitivity)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if matcher.Match(test.name) != test.expected {
+			t.Fatal("Expected", test.expected, "for", test.term, "matching", test.name, "with regex:", test.useRegex, "case:", test.caseSensitivity)
+		}
+	}
+
+	_, err := NewSearchMatcher("(", true, CASE_SENSITIVE)
+	if err == nil {
+		t.Fatal("Expected an error for an invalid regex")
+	}
+
+	matcher, _ := NewSearchMatcher("a", false, CASE_INSENSITIVE)
+	ranges := matcher.MatchRanges("bAnana")
+	if !slices.EqualFunc(ranges, [][]int{{1, 2}, {3, 4}, {5, 6}}, slices.Equal) {
+		t.Fatal("Unexpected match ranges", ranges)
+	}
+
+	// The Kelvin sign "\u212A" matches "k" case-insensitively, but is 3 bytes long
+	matcher, _ = NewSearchMatcher("k", false, CASE_INSENSITIVE)
+	ranges = matcher.MatchRanges("a\u212Abk")
+	if !slices.EqualFunc(ranges, [][]int{{1, 4}, {5, 6}}, slices.Equal) {
+		t.Fatal("Unexpected match ranges", ranges)
+	}
+}
+
+func TestGlobSearchMatcher(t *testing.T) {
+	type TestCase struct {
+		pattern         string
+		caseSensitivity string
+		name            string
+		expected        bool
+	}
+
+	tests := []TestCase{
+		{"*.log", CASE_INSENSITIVE, "server.LOG", true},
+		{"*.log", CASE_SENSITIVE, "server.LOG", false},
+		{"*.log", CASE_SMART, "server.log.gz", false},
+		{"app-?.log", CASE_SMART, "app-1.log", true},
+		{"[ab]*", CASE_SENSITIVE, "banana", true},
+		// Names are paths relative to the folder in file list mode
+		{"*.go", CASE_SENSITIVE, filepath.Join("folder", "main.go"), true},
+		{"fo*", CASE_SENSITIVE, filepath.Join("folder", "main.go"), false},
+		{filepath.Join("folder", "*.go"), CASE_SENSITIVE, filepath.Join("folder", "main.go"), true},
+		{filepath.Join("folder", "*.go"), CASE_SENSITIVE, filepath.Join("other", "main.go"), false},
+	}
+
+	for _, test := range tests {
+		matcher, err := NewGlobSearchMatcher(test.pattern, test.caseSensitivity)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if matcher.Match(test.name) != test.expected {
+			t.Fatal("Expected", test.expected, "for", test.pattern, "matching", test.name, "case:", test.caseSensitivity)
+		}
+	}
+
+	_, err := NewGlobSearchMatcher("[", CASE_SENSITIVE)
+	if err == nil {
+		t.Fatal("Expected an error for an invalid pattern")
+	}
+}
+
+func TestNextMatchingIndex(t *testing.T) {
+	matching := []bool{false, true, false, true, false}
+	matches := func(i int) bool { return matching[i] }
+
+	type TestCase struct {
+		start        int
+		forward      bool
+		includeStart bool
+		expected     int
+	}
+
+	tests := []TestCase{
+		{1, true, true, 1},
+		{1, true, false, 3},
+		{3, true, false, 1}, // Wraps around
+		{3, false, false, 1},
+		{1, false, false, 3}, // Wraps around
+		{0, false, true, 3},
+	}
+
+	for _, test := range tests {
+		index, found := NextMatchingIndex(len(matching), test.start, test.forward, test.includeStart, matches)
+		if !found || index != test.expected {
+			t.Fatal("Expected", test.expected, "but got", index, "for", test)
+		}
+	}
+
+	_, found := NextMatchingIndex(0, 0, true, true, matches)
+	if found {
+		t.Fatal("Expected nothing to be found when there are no elements")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+)
+
+// The state of a tab, each tab has its own working directory, history and selection.
+// The yanked files are shared between all tabs, so you can copy or cut in one tab and paste in another
+type Tab struct {
+	wd         string
+	sel        string
+	history    *History
+	navigation *NavigationStack
+	selected   map[string]bool
+	flattened  bool
+}
+
+// Stores the state of the current tab in fen.tabs
+func (fen *Fen) saveCurrentTab() {
+	tab := fen.tabs[fen.currentTab]
+	tab.wd = fen.wd
+	tab.sel = fen.sel
+	tab.history = fen.history
+	tab.navigation = fen.navigation
+	tab.selected = fen.selected
+	tab.flattened = fen.flattened
+}
+
+// Restores the state of the tab at index, without saving the current tab first
+func (fen *Fen) loadTab(index int) {
+	tab := fen.tabs[index]
+	fen.currentTab = index
+	fen.wd = tab.wd
+	fen.sel = tab.sel
+	fen.history = tab.history
+	fen.navigation = tab.navigation
+	fen.selected = tab.selected
+
+	// The selection indices don't carry over between tabs
+	fen.DisableSelectingWithV()
+
+	if fen.flattened != tab.flattened {
+		fen.ToggleFlattened()
+	}
+}
+
+// Opens a new tab after the current one, starting in the current working directory
+func (fen *Fen) NewTab() {
+	fen.saveCurrentTab()
+
+	history := &History{}
+	history.AddToHistory(fen.sel)
+
+	navigation := &NavigationStack{}
+	navigation.Visit(fen.wd)
+
+	tab := &Tab{
+		wd:         fen.wd,
+		sel:        fen.sel,
+		history:    history,
+		navigation: navigation,
+		selected:   map[string]bool{},
+		flattened:  fen.flattened,
+	}
+
+	fen.tabs = append(fen.tabs[:fen.currentTab+1], append([]*Tab{tab}, fen.tabs[fen.currentTab+1:]...)...)
+	fen.loadTab(fen.currentTab + 1)
+}
+
+// Closes the current tab and switches to the tab before it, returns an error if it is the last tab
+func (fen *Fen) CloseTab() error {
+	if len(fen.tabs) <= 1 {
+		return errors.New("Can't close the last tab")
+	}
+
+	fen.tabs = append(fen.tabs[:fen.currentTab], fen.tabs[fen.currentTab+1:]...)
+	index := max(0, fen.currentTab-1)
+
+	// The tab in the other panel can't also be the current tab
+	if fen.dualPane && fen.tabs[index] == fen.otherTab {
+		if len(fen.tabs) < 2 {
+			fen.ToggleDualPane()
+		} else {
+			index = (index + 1) % len(fen.tabs)
+		}
+	}
+
+	fen.loadTab(index)
+	return nil
+}
+
+// Switches to the tab at index, wrapping around
+func (fen *Fen) SwitchTab(index int) {
+	index = (index + len(fen.tabs)) % len(fen.tabs)
+	if index == fen.currentTab {
+		return
+	}
+
+	fen.saveCurrentTab()
+	fen.loadTab(index)
+}
+
+// The text shown in the bottom bar after changing tabs, like "Tab 2/3"
+func (fen *Fen) TabText() string {
+	return "Tab " + strconv.Itoa(fen.currentTab+1) + "/" + strconv.Itoa(len(fen.tabs))
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestTabs(t *testing.T) {
+	fen := Fen{wd: "/a", sel: "/a/file", history: &History{}, selected: map[string]bool{}, tabs: []*Tab{{}}}
+
+	fen.NewTab()
+	if fen.currentTab != 1 || len(fen.tabs) != 2 {
+		t.Fatal("Expected to be in the second of 2 tabs, but got tab", fen.currentTab, "of", len(fen.tabs))
+	}
+	if fen.wd != "/a" || fen.sel != "/a/file" {
+		t.Fatal("Expected the new tab to start in the same folder, but got", fen.wd, fen.sel)
+	}
+
+	fen.wd = "/b"
+	fen.sel = "/b/other"
+	fen.selected["/b/other"] = true
+
+	fen.SwitchTab(fen.currentTab + 1) // Wraps around
+	if fen.currentTab != 0 || fen.wd != "/a" || len(fen.selected) != 0 {
+		t.Fatal("Expected the first tab to be unchanged, but got", fen.currentTab, fen.wd, fen.selected)
+	}
+
+	fen.SwitchTab(1)
+	if fen.wd != "/b" || fen.sel != "/b/other" || !fen.selected["/b/other"] {
+		t.Fatal("Expected the second tab to be restored, but got", fen.wd, fen.sel, fen.selected)
+	}
+
+	err := fen.CloseTab()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fen.currentTab != 0 || len(fen.tabs) != 1 || fen.wd != "/a" {
+		t.Fatal("Expected to be back in the first tab, but got", fen.currentTab, len(fen.tabs), fen.wd)
+	}
+
+	err = fen.CloseTab()
+	if err == nil {
+		t.Fatal("Expected an error when closing the last tab")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/gdamore/tcell/v2"
@@ -33,6 +34,13 @@ func (topBar *TopBar) Draw(screen tcell.Screen) {
 
 	x, y, w, _ := topBar.GetInnerRect()
 
+	// A different background color makes it obvious file list mode is enabled
+	if topBar.fen.flattened {
+		for i := 0; i < w; i++ {
+			screen.SetContent(x+i, y, ' ', nil, tcell.StyleDefault.Background(tcell.ColorPurple))
+		}
+	}
+
 	path := topBar.fen.sel
 
 	var username string
@@ -78,7 +86,57 @@ func (topBar *TopBar) Draw(screen tcell.Screen) {
 		tview.Print(screen, "« "+topBar.additionalText, x+usernameAndHostnameLength+1+pathPrintedLength+1, y, w, tview.AlignLeft, tcell.ColorDefault)
 	}
 
+	rightText := []string{}
+
+	if topBar.fen.config.GitStatus {
+		repositoryPath, err := topBar.fen.gitStatusHandler.TryFindParentGitRepository(topBar.fen.wd)
+		if err == nil {
+			gitText := GitRepositoryInfoText(topBar.fen.gitStatusHandler.RepositoryInfo(repositoryPath), topBar.fen.gitStatusHandler.RepositoryState(repositoryPath))
+			if gitText != "" {
+				rightText = append(rightText, gitText)
+			}
+		}
+	}
+
+	// The tab strip is only shown when there are multiple tabs
+	if len(topBar.fen.tabs) > 1 {
+		tabStrip := ""
+		for i, tab := range topBar.fen.tabs {
+			wd := tab.wd
+			if i == topBar.fen.currentTab {
+				wd = topBar.fen.wd
+			}
+
+			tabText := " " + strconv.Itoa(i+1) + " " + tview.Escape(filepath.Base(wd)) + " "
+			if i == topBar.fen.currentTab {
+				tabText = "[black:white:b]" + tabText + "[-:-:-]"
+			}
+			tabStrip += tabText
+		}
+		rightText = append(rightText, tabStrip)
+	}
 	if topBar.fen.runningGitStatus {
-		tview.Print(screen, "Refreshing Git status...", x, y, w, tview.AlignRight, tcell.ColorDefault)
+		rightText = append(rightText, "Refreshing Git status...")
+	}
+
+	filter, hasFilter := topBar.fen.filters[topBar.fen.wd]
+	if hasFilter {
+		rightText = append(rightText, "[::b]Filter:[::-] "+tview.Escape(filter.term))
+	}
+
+	if topBar.fen.changedFilesOnly {
+		rightText = append(rightText, "[::b]Changed files only")
+	}
+
+	if topBar.fen.flattened {
+		if topBar.fen.middlePane.flattenedLoading {
+			rightText = append(rightText, "[::b]File list mode[::-] (loading...)")
+		} else {
+			rightText = append(rightText, "[::b]File list mode")
+		}
+	}
+
+	if len(rightText) > 0 {
+		tview.Print(screen, strings.Join(rightText, "  "), x, y, w, tview.AlignRight, tcell.ColorDefault)
 	}
 }
@@ -5,10 +5,12 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"cmp"
 	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"math"
 	"math/rand"
 	"os"
@@ -23,6 +25,7 @@ import (
 
 	"github.com/charlievieth/strcase"
 	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
 	"github.com/rivo/tview"
 	lua "github.com/yuin/gopher-lua"
 	"golang.org/x/term"
@@ -32,6 +35,17 @@ import (
 const pressAnyKeyToContinueText = "Press any key to continue..."
 const pressEnterToContinueText = "Press Enter to continue..."
 
+// Returns the folder where fen stores its cache files, like "/home/YOUR_USER/.cache/fen" on Linux
+// The folder is not created by this function
+func FenCacheDir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(userCacheDir, "fen"), nil
+}
+
 // Trims the last decimals up to maxDecimals, does nothing if maxDecimals is less than 0, e.g -1
 func trimLastDecimals(numberString string, maxDecimals int) string {
 	if maxDecimals < 0 {
@@ -927,7 +941,8 @@ func FilenameInvisibleCharactersAsCodeHighlighted(filename, defaultStyle string)
 }
 
 // Returns the length printed
-func PrintFilenameInvisibleCharactersAsCodeHighlighted(screen tcell.Screen, x, y, maxWidth int, filename string, style tcell.Style) int {
+// matchRanges are the start and end byte indices of search matches in filename, which are drawn in orange
+func PrintFilenameInvisibleCharactersAsCodeHighlighted(screen tcell.Screen, x, y, maxWidth int, filename string, style tcell.Style, matchRanges [][]int) int {
 	if filename == "" {
 		panic("PrintFilenameInvisibleCharactersAsCodeHighlighted got empty filename")
 	}
@@ -993,8 +1008,24 @@ func PrintFilenameInvisibleCharactersAsCodeHighlighted(screen tcell.Screen, x, y
 			continue
 		}
 
-		screen.SetContent(x+offset, y, c, nil, style)
-		offset++
+		// Wide characters like CJK take up 2 cells
+		charWidth := max(1, runewidth.RuneWidth(c))
+		if offset+charWidth > maxWidth-2 {
+			screen.SetContent(x+offset, y, missingSpaceRune, nil, style)
+			offset++
+			return offset
+		}
+
+		charStyle := style
+		for _, matchRange := range matchRanges {
+			if i >= matchRange[0] && i < matchRange[1] {
+				charStyle = style.Foreground(tcell.ColorOrange)
+				break
+			}
+		}
+
+		screen.SetContent(x+offset, y, c, nil, charStyle)
+		offset += charWidth
 	}
 
 	return offset
@@ -1195,6 +1226,58 @@ func NumberPrefix(s string) string {
 	return s[:count]
 }
 
+// Compares strings like strings.Compare(), except numbers anywhere in them are compared by their value.
+// e.g. "v1.9" < "v1.10" and "file2" < "file10"
+func CompareNatural(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		aIsDigit := a[i] >= '0' && a[i] <= '9'
+		bIsDigit := b[j] >= '0' && b[j] <= '9'
+
+		if aIsDigit && bIsDigit {
+			aNumber := NumberPrefix(a[i:])
+			bNumber := NumberPrefix(b[j:])
+			result := CompareNumericalStrings(aNumber, bNumber)
+			if result != 0 {
+				return result
+			}
+
+			i += len(aNumber)
+			j += len(bNumber)
+			continue
+		}
+
+		if a[i] != b[j] {
+			return cmp.Compare(a[i], b[j])
+		}
+
+		i++
+		j++
+	}
+
+	result := cmp.Compare(len(a)-i, len(b)-j)
+	if result != 0 {
+		return result
+	}
+
+	// Equal numbers with a different amount of leading zeros, like "01" and "1"
+	return strings.Compare(a, b)
+}
+
+// Returns the order used by SORT_TYPE, folders first
+func FileTypeOrder(fileType fs.FileMode) int {
+	switch {
+	case fileType.IsDir():
+		return 0
+	case fileType&fs.ModeSymlink != 0:
+		return 1
+	case fileType.IsRegular():
+		return 2
+	}
+
+	return 3
+}
+
 // Compares two positive numerical strings.
 // Returns  0 if num1 == num2
 // Returns  1 if num1 > num2
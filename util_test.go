@@ -391,3 +391,43 @@ func TestSpreadArrayIntoSlicesForGoroutines(t *testing.T) {
 		}
 	}
 }
+
+func TestCompareNatural(t *testing.T) {
+	type TestCase struct {
+		a        string
+		b        string
+		expected int
+	}
+
+	tests := []TestCase{
+		{"v1.9", "v1.10", -1},
+		{"v1.10", "v1.9", 1},
+		{"file2.txt", "file10.txt", -1},
+		{"file10", "file10", 0},
+		{"file", "file1", -1},
+		{"a1", "b0", -1},
+		{"01", "1", -1}, // Leading zeros are compared last
+		{"release-2.0.0.tar.gz", "release-10.0.0.tar.gz", -1},
+	}
+
+	for _, test := range tests {
+		got := CompareNatural(test.a, test.b)
+		if got != test.expected {
+			t.Fatal("Expected", test.expected, "comparing", test.a, "and", test.b, "but got:", got)
+		}
+	}
+}
+
+func TestValidateSortBy(t *testing.T) {
+	for _, valid := range []string{SORT_NONE, SORT_NATURAL, "file-extension,size", "type,natural"} {
+		if err := ValidateSortBy(valid); err != nil {
+			t.Fatal("Expected", valid, "to be valid, but got:", err)
+		}
+	}
+
+	for _, invalid := range []string{"", "bogus", "size,", "none,size"} {
+		if err := ValidateSortBy(invalid); err == nil {
+			t.Fatal("Expected", invalid, "to be invalid")
+		}
+	}
+}
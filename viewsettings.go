@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// How a folder is shown, changed at runtime and remembered across sessions when fen.remember_view_settings is enabled.
+// A nil field means the value from the config is used
+type FolderViewSettings struct {
+	SortBy      *string       `json:"sort_by,omitempty"`
+	SortReverse *bool         `json:"sort_reverse,omitempty"`
+	HiddenFiles *bool         `json:"hidden_files,omitempty"`
+	Filter      *FolderFilter `json:"filter,omitempty"`
+}
+
+type FolderFilter struct {
+	Term string `json:"term"`
+	Mode string `json:"mode"` // One of the FILTER_ constants
+}
+
+func (s *FolderViewSettings) isEmpty() bool {
+	return s.SortBy == nil && s.SortReverse == nil && s.HiddenFiles == nil && s.Filter == nil
+}
+
+// Returns the sorting used when the folder has no remembered sort, fen.folder_sort_by overrides fen.sort_by
+func (fen *Fen) configSortByIn(folder string) string {
+	for _, entry := range fen.config.FolderSortBy {
+		if filepath.Clean(ExpandTilde(entry.Folder)) == folder {
+			return entry.SortBy
+		}
+	}
+
+	return fen.config.SortBy
+}
+
+func (fen *Fen) SortByIn(folder string) string {
+	settings, ok := fen.folderViewSettings[folder]
+	if ok && settings.SortBy != nil {
+		return *settings.SortBy
+	}
+
+	return fen.configSortByIn(folder)
+}
+
+func (fen *Fen) SortReverseIn(folder string) bool {
+	settings, ok := fen.folderViewSettings[folder]
+	if ok && settings.SortReverse != nil {
+		return *settings.SortReverse
+	}
+
+	return fen.config.SortReverse
+}
+
+func (fen *Fen) HiddenFilesIn(folder string) bool {
+	settings, ok := fen.folderViewSettings[folder]
+	if ok && settings.HiddenFiles != nil {
+		return *settings.HiddenFiles
+	}
+
+	return fen.config.HiddenFiles
+}
+
+// Calls change on the view settings of folder, and saves them.
+// Settings equal to the config are cleared, so the folder follows later changes to the config
+func (fen *Fen) changeFolderViewSettings(folder string, change func(settings *FolderViewSettings)) {
+	if fen.folderViewSettings == nil {
+		fen.folderViewSettings = make(map[string]*FolderViewSettings)
+	}
+
+	settings, ok := fen.folderViewSettings[folder]
+	if !ok {
+		settings = &FolderViewSettings{}
+	}
+
+	change(settings)
+
+	if settings.SortBy != nil && *settings.SortBy == fen.configSortByIn(folder) {
+		settings.SortBy = nil
+	}
+	if settings.SortReverse != nil && *settings.SortReverse == fen.config.SortReverse {
+		settings.SortReverse = nil
+	}
+	if settings.HiddenFiles != nil && *settings.HiddenFiles == fen.config.HiddenFiles {
+		settings.HiddenFiles = nil
+	}
+
+	if settings.isEmpty() {
+		delete(fen.folderViewSettings, folder)
+	} else {
+		fen.folderViewSettings[folder] = settings
+	}
+
+	fen.saveFolderViewSettings()
+}
+
+// Changes the sorting of folder, or of every folder if fen.remember_view_settings is disabled
+func (fen *Fen) SetSortBy(folder, sortBy string) {
+	if !fen.config.RememberViewSettings {
+		fen.config.SortBy = sortBy
+		return
+	}
+
+	fen.changeFolderViewSettings(folder, func(settings *FolderViewSettings) {
+		settings.SortBy = &sortBy
+	})
+}
+
+// Switches to the next sorting in ValidSortByValues, returns the new sorting
+func (fen *Fen) CycleSortBy(folder string) string {
+	// A combination of sort keys, like "file-extension,size" isn't in the list, so it goes back to the first one
+	index := slices.Index(ValidSortByValues[:], fen.SortByIn(folder))
+	sortBy := ValidSortByValues[(index+1)%len(ValidSortByValues)]
+
+	fen.SetSortBy(folder, sortBy)
+	return sortBy
+}
+
+// Returns the new value, changes every folder if fen.remember_view_settings is disabled
+func (fen *Fen) ToggleSortReverse(folder string) bool {
+	sortReverse := !fen.SortReverseIn(folder)
+	if !fen.config.RememberViewSettings {
+		fen.config.SortReverse = sortReverse
+		return sortReverse
+	}
+
+	fen.changeFolderViewSettings(folder, func(settings *FolderViewSettings) {
+		settings.SortReverse = &sortReverse
+	})
+	return sortReverse
+}
+
+// Returns the new value, changes every folder if fen.remember_view_settings is disabled
+func (fen *Fen) ToggleHiddenFiles(folder string) bool {
+	hiddenFiles := !fen.HiddenFilesIn(folder)
+	if !fen.config.RememberViewSettings {
+		fen.config.HiddenFiles = hiddenFiles
+		return hiddenFiles
+	}
+
+	fen.changeFolderViewSettings(folder, func(settings *FolderViewSettings) {
+		settings.HiddenFiles = &hiddenFiles
+	})
+	return hiddenFiles
+}
+
+// Remembers the filter set with Fen.SetFilter(), a nil filter removes it
+func (fen *Fen) rememberFilter(folder string, filter *SearchMatcher) {
+	if !fen.config.RememberViewSettings {
+		return
+	}
+
+	fen.changeFolderViewSettings(folder, func(settings *FolderViewSettings) {
+		if filter == nil {
+			settings.Filter = nil
+		} else {
+			settings.Filter = &FolderFilter{Term: filter.term, Mode: filter.filterMode()}
+		}
+	})
+}
+
+func folderViewSettingsFilePath() (string, error) {
+	cacheDir, err := FenCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cacheDir, "folder_view_settings.json"), nil
+}
+
+func (fen *Fen) saveFolderViewSettings() {
+	if fen.config.NoWrite {
+		return
+	}
+
+	path, err := folderViewSettingsFilePath()
+	if err != nil {
+		return
+	}
+
+	data, err := json.Marshal(fen.folderViewSettings)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return
+	}
+
+	// The filters can reveal what the user was looking for, so only the user can read it
+	err = os.WriteFile(path, data, 0o600)
+	if err != nil {
+		fen.bottomBar.TemporarilyShowTextInstead("Failed to save folder view settings: " + err.Error())
+	}
+}
+
+// Loads the remembered view settings and filters, does nothing if fen.remember_view_settings is disabled
+func (fen *Fen) loadFolderViewSettings() {
+	fen.folderViewSettings = make(map[string]*FolderViewSettings)
+	if !fen.config.RememberViewSettings {
+		return
+	}
+
+	path, err := folderViewSettingsFilePath()
+	if err != nil {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	// If the file is corrupt, we just start over without any remembered settings
+	_ = json.Unmarshal(data, &fen.folderViewSettings)
+	if fen.folderViewSettings == nil {
+		fen.folderViewSettings = make(map[string]*FolderViewSettings)
+	}
+
+	if fen.filters == nil {
+		fen.filters = make(map[string]*SearchMatcher)
+	}
+
+	for folder, settings := range fen.folderViewSettings {
+		if settings == nil {
+			delete(fen.folderViewSettings, folder)
+			continue
+		}
+
+		// The config might have changed since they were saved
+		if settings.SortBy != nil && ValidateSortBy(*settings.SortBy) != nil {
+			settings.SortBy = nil
+		}
+
+		if settings.Filter != nil {
+			filter, err := NewFilterSearchMatcher(settings.Filter.Term, settings.Filter.Mode, fen.config.SearchCase)
+			if err != nil {
+				settings.Filter = nil
+			} else {
+				fen.filters[folder] = filter
+			}
+		}
+	}
+}
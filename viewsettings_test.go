@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestFolderViewSettings(t *testing.T) {
+	fen := Fen{config: Config{NoWrite: true, RememberViewSettings: true, SortBy: SORT_ALPHABETICAL}}
+
+	fen.SetSortBy("/a", SORT_MODIFIED)
+	if fen.SortByIn("/a") != SORT_MODIFIED || fen.SortByIn("/b") != SORT_ALPHABETICAL {
+		t.Fatal("Expected only /a to be sorted by modified, but got", fen.SortByIn("/a"), fen.SortByIn("/b"))
+	}
+
+	if !fen.ToggleHiddenFiles("/a") || !fen.HiddenFilesIn("/a") || fen.HiddenFilesIn("/b") {
+		t.Fatal("Expected only /a to show hidden files")
+	}
+
+	filter, err := NewFilterSearchMatcher("*.go", FILTER_GLOB, CASE_INSENSITIVE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fen.SetFilter("/a", filter)
+	if fen.folderViewSettings["/a"].Filter == nil || *fen.folderViewSettings["/a"].Filter != (FolderFilter{Term: "*.go", Mode: FILTER_GLOB}) {
+		t.Fatal("Expected the filter to be remembered, but got", fen.folderViewSettings["/a"].Filter)
+	}
+
+	// Going back to the config values forgets the folder
+	fen.SetSortBy("/a", SORT_ALPHABETICAL)
+	fen.ToggleHiddenFiles("/a")
+	fen.SetFilter("/a", nil)
+	if _, ok := fen.folderViewSettings["/a"]; ok {
+		t.Fatal("Expected /a to be forgotten, but got", fen.folderViewSettings["/a"])
+	}
+
+	// Changes every folder when disabled
+	fen.config.RememberViewSettings = false
+	fen.ToggleSortReverse("/a")
+	if !fen.SortReverseIn("/b") || len(fen.folderViewSettings) != 0 {
+		t.Fatal("Expected reverse sorting in every folder")
+	}
+}
+
+func TestCycleSortBy(t *testing.T) {
+	fen := Fen{config: Config{NoWrite: true, RememberViewSettings: true, SortBy: "file-extension,size"}}
+
+	// A combination of sort keys goes back to the first one
+	if fen.CycleSortBy("/a") != ValidSortByValues[0] {
+		t.Fatal("Expected", ValidSortByValues[0], "but got", fen.SortByIn("/a"))
+	}
+	if fen.CycleSortBy("/a") != ValidSortByValues[1] {
+		t.Fatal("Expected", ValidSortByValues[1], "but got", fen.SortByIn("/a"))
+	}
+}